@@ -0,0 +1,219 @@
+package admission
+
+import (
+	"bot/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Score конкурсные показатели поступающего в порядке, в котором они сравниваются
+type Score struct {
+	WithoutExams  bool  // Поступает без вступительных испытаний (БВИ), ранжируется выше всех
+	PriorityRight bool  // Преимущественное право по ч. 10 ст. 71, ранжируется сразу после БВИ
+	Total         int   // Сумма конкурсных баллов
+	Subjects      int   // Сумма баллов по предметам
+	BySubject     []int // Баллы по предметам в порядке приоритета вступительных испытаний
+	Achievements  int   // Баллы за индивидуальные достижения
+	Preferential  bool  // Преимущественное право по ч. 9 ст. 71, учитывается при равенстве всех баллов
+}
+
+// ParseScore разбирает показатели поступающего. Второе значение false, если не удалось разобрать
+// сумму баллов у поступающего со вступительными испытаниями - такого нельзя сравнить с остальными
+func ParseScore(entry models.StudentEntry) (Score, bool) {
+	category := entry.Category()
+	score := Score{
+		WithoutExams:  category == models.CategoryBVI,
+		PriorityRight: category == models.CategoryPriority,
+		Subjects:      parseNumber(entry.SubjectScore),
+		BySubject:     subjectScores(entry),
+		Achievements:  parseNumber(entry.GeneralAchievements),
+		Preferential:  category == models.CategoryPreferential,
+	}
+
+	total, err := strconv.Atoi(strings.TrimSpace(entry.TotalScore))
+	if err != nil {
+		return score, score.WithoutExams
+	}
+	score.Total = total
+	return score, true
+}
+
+// subjectScores возвращает баллы по предметам в порядке приоритета вступительных испытаний:
+// столбцы предметов на странице идут в этом порядке. В версиях списка, сохраненных до разбора
+// испытаний по столбцам, есть только математика, информатика и русский язык
+func subjectScores(entry models.StudentEntry) []int {
+	if len(entry.Subjects) == 0 {
+		return []int{parseNumber(entry.Math), parseNumber(entry.IT), parseNumber(entry.Russian)}
+	}
+	scores := make([]int, len(entry.Subjects))
+	for i, subject := range entry.Subjects {
+		scores[i] = parseNumber(subject.Score)
+	}
+	return scores
+}
+
+// Compare сравнивает показатели: положительное значение, если s выше в конкурсе, чем other,
+// отрицательное - если ниже, 0 - если поступающие полностью равны
+func (s Score) Compare(other Score) int {
+	if s.WithoutExams != other.WithoutExams {
+		if s.WithoutExams {
+			return 1
+		}
+		return -1
+	}
+	if s.PriorityRight != other.PriorityRight {
+		if s.PriorityRight {
+			return 1
+		}
+		return -1
+	}
+	if s.Total != other.Total {
+		return s.Total - other.Total
+	}
+	if s.Subjects != other.Subjects {
+		return s.Subjects - other.Subjects
+	}
+	for i := 0; i < len(s.BySubject) && i < len(other.BySubject); i++ {
+		if s.BySubject[i] != other.BySubject[i] {
+			return s.BySubject[i] - other.BySubject[i]
+		}
+	}
+	if s.Achievements != other.Achievements {
+		return s.Achievements - other.Achievements
+	}
+	if s.Preferential != other.Preferential {
+		if s.Preferential {
+			return 1
+		}
+		return -1
+	}
+	return 0
+}
+
+// Rank возвращает поступающих в порядке конкурсного ранжирования: БВИ, преимущественное право
+// по ч. 10, сумма баллов, сумма по предметам, баллы по предметам по приоритету, индивидуальные
+// достижения, преимущественное право по ч. 9. Поступающие с неразобранными баллами идут
+// в конце в порядке конкурсного списка
+func Rank(entries []models.StudentEntry) []models.StudentEntry {
+	type ranked struct {
+		entry models.StudentEntry
+		score Score
+		valid bool
+	}
+	items := make([]ranked, len(entries))
+	for i, entry := range entries {
+		score, valid := ParseScore(entry)
+		items[i] = ranked{entry: entry, score: score, valid: valid}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].valid != items[j].valid {
+			return items[i].valid
+		}
+		if !items[i].valid {
+			return false
+		}
+		return items[i].score.Compare(items[j].score) > 0
+	})
+
+	result := make([]models.StudentEntry, len(items))
+	for i, item := range items {
+		result[i] = item.entry
+	}
+	return result
+}
+
+// HighPassingPriority оставляет поступающих с галочкой в столбце "Это высший проходной приоритет":
+// остальные проходят на направление с более высоким приоритетом и здесь места не займут
+func HighPassingPriority(entries []models.StudentEntry) []models.StudentEntry {
+	var filtered []models.StudentEntry
+	for _, entry := range entries {
+		if strings.Contains(entry.IsHighPassingPriority, "✓") {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered
+}
+
+// Position возвращает позицию поступающего с местом rank (с 1) в ранжированном списке и количество
+// мест, за которые он конкурирует. Поступающие вне общего конкурса считаются среди всех мест.
+// Из позиции вычитаются только БВИ и ч. 10, стоящие выше: с неразобранными баллами они оказываются
+// в конце списка, но места все равно занимают, поэтому учитываются только в количестве мест
+func Position(ranked []models.StudentEntry, rank int, places int) (int, int) {
+	if ranked[rank-1].Category().TakesSeatFirst() {
+		return rank, places
+	}
+	_, generalPlaces := GeneralCompetition(ranked, places)
+	position := rank
+	for _, entry := range ranked[:rank-1] {
+		if entry.Category().TakesSeatFirst() {
+			position--
+		}
+	}
+	return position, generalPlaces
+}
+
+// GeneralCompetition отделяет от ранжированного списка поступающих, которые занимают места раньше
+// общего конкурса (БВИ и ч. 10), и возвращает общий конкурс и количество оставшихся ему мест
+func GeneralCompetition(ranked []models.StudentEntry, places int) ([]models.StudentEntry, int) {
+	var general []models.StudentEntry
+	first := 0
+	for _, entry := range ranked {
+		if entry.Category().TakesSeatFirst() {
+			first++
+			continue
+		}
+		general = append(general, entry)
+	}
+
+	generalPlaces := places - first
+	if generalPlaces < 0 {
+		generalPlaces = 0
+	}
+	return general, generalPlaces
+}
+
+// CutOff проходной балл конкурсной группы
+type CutOff struct {
+	Status models.CutOffStatus
+	Score  int // Сумма баллов последнего проходящего, если Status == models.CutOffKnown
+	Tied   int // Сколько поступающих с полностью равными показателями делят последнее проходное место
+}
+
+// CalculateCutOff вычисляет проходной балл по ранжированному списку и количеству мест
+func CalculateCutOff(ranked []models.StudentEntry, places int) CutOff {
+	if places <= 0 {
+		return CutOff{Status: models.CutOffUnknown}
+	}
+	if len(ranked) < places {
+		// Места достанутся всем: проходной балл определяется минимумом вступительных испытаний
+		return CutOff{Status: models.CutOffNoCompetition}
+	}
+
+	last, ok := ParseScore(ranked[places-1])
+	if !ok || last.WithoutExams {
+		return CutOff{Status: models.CutOffUnknown}
+	}
+	cutOff := CutOff{Status: models.CutOffKnown, Score: last.Total}
+
+	// Равенство важно, только если равные поступающие оказались по обе стороны границы
+	if len(ranked) > places {
+		if next, ok := ParseScore(ranked[places]); ok && next.Compare(last) == 0 {
+			for _, entry := range ranked {
+				if score, ok := ParseScore(entry); ok && score.Compare(last) == 0 {
+					cutOff.Tied++
+				}
+			}
+		}
+	}
+	return cutOff
+}
+
+func parseNumber(value string) int {
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0
+	}
+	return number
+}
@@ -0,0 +1,156 @@
+package admission
+
+import (
+	"bot/models"
+	"slices"
+	"testing"
+)
+
+func entry(code, total, subjects string) models.StudentEntry {
+	return models.StudentEntry{UniqueCode: code, TotalScore: total, SubjectScore: subjects}
+}
+
+func codes(entries []models.StudentEntry) []string {
+	result := make([]string, len(entries))
+	for i, entry := range entries {
+		result[i] = entry.UniqueCode
+	}
+	return result
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []models.StudentEntry
+		want    []string
+	}{
+		{
+			name:    "по сумме баллов",
+			entries: []models.StudentEntry{entry("1", "250", "240"), entry("2", "270", "260"), entry("3", "260", "250")},
+			want:    []string{"2", "3", "1"},
+		},
+		{
+			name:    "при равной сумме по сумме по предметам",
+			entries: []models.StudentEntry{entry("1", "250", "240"), entry("2", "250", "245")},
+			want:    []string{"2", "1"},
+		},
+		{
+			name: "в старых версиях списка по математике, информатике и русскому языку",
+			entries: []models.StudentEntry{
+				{UniqueCode: "1", TotalScore: "250", SubjectScore: "250", Math: "80", IT: "90", Russian: "80"},
+				{UniqueCode: "2", TotalScore: "250", SubjectScore: "250", Math: "85", IT: "80", Russian: "85"},
+			},
+			want: []string{"2", "1"},
+		},
+		{
+			name: "при равенстве баллов по предметам в порядке столбцов страницы",
+			entries: []models.StudentEntry{
+				{UniqueCode: "1", TotalScore: "250", SubjectScore: "250", Subjects: []models.SubjectScore{{Name: "РусЯз", Score: "80"}, {Name: "Химия", Score: "90"}}},
+				{UniqueCode: "2", TotalScore: "250", SubjectScore: "250", Subjects: []models.SubjectScore{{Name: "РусЯз", Score: "85"}, {Name: "Химия", Score: "70"}}},
+			},
+			want: []string{"2", "1"},
+		},
+		{
+			name: "ч. 9 только при равенстве всех баллов",
+			entries: []models.StudentEntry{
+				{UniqueCode: "1", TotalScore: "250", SubjectScore: "250", PPR9: "да"},
+				entry("2", "250", "250"),
+				entry("3", "251", "251"),
+			},
+			want: []string{"3", "1", "2"},
+		},
+		{
+			name: "БВИ и ч. 10 выше всех",
+			entries: []models.StudentEntry{
+				entry("1", "300", "290"),
+				{UniqueCode: "2", TotalScore: "200", SubjectScore: "200", PPR10: "да"},
+				{UniqueCode: "3", BVIBasis: "Олимпиада"},
+			},
+			want: []string{"3", "2", "1"},
+		},
+		{
+			name:    "неразобранные баллы в конце в порядке списка",
+			entries: []models.StudentEntry{entry("1", "—", ""), entry("2", "200", "200"), entry("3", "", "")},
+			want:    []string{"2", "1", "3"},
+		},
+		{
+			name:    "полностью равные в порядке списка",
+			entries: []models.StudentEntry{entry("1", "250", "250"), entry("2", "250", "250")},
+			want:    []string{"1", "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codes(Rank(tt.entries)); !slices.Equal(got, tt.want) {
+				t.Errorf("порядок %v, ожидался %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateCutOff(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranked []models.StudentEntry
+		places int
+		want   CutOff
+	}{
+		{
+			name:   "последний проходящий",
+			ranked: []models.StudentEntry{entry("1", "270", "260"), entry("2", "260", "250"), entry("3", "250", "240")},
+			places: 2,
+			want:   CutOff{Status: models.CutOffKnown, Score: 260},
+		},
+		{
+			name:   "равные по обе стороны границы",
+			ranked: []models.StudentEntry{entry("1", "270", "260"), entry("2", "260", "250"), entry("3", "260", "250"), entry("4", "250", "240")},
+			places: 2,
+			want:   CutOff{Status: models.CutOffKnown, Score: 260, Tied: 2},
+		},
+		{
+			name:   "равная сумма, но разные баллы по предметам",
+			ranked: []models.StudentEntry{entry("1", "270", "260"), entry("2", "260", "255"), entry("3", "260", "250")},
+			places: 2,
+			want:   CutOff{Status: models.CutOffKnown, Score: 260},
+		},
+		{
+			name:   "мест столько же, сколько поступающих",
+			ranked: []models.StudentEntry{entry("1", "270", "260"), entry("2", "260", "250")},
+			places: 2,
+			want:   CutOff{Status: models.CutOffKnown, Score: 260},
+		},
+		{
+			name:   "мест больше, чем поступающих",
+			ranked: []models.StudentEntry{entry("1", "270", "260")},
+			places: 2,
+			want:   CutOff{Status: models.CutOffNoCompetition},
+		},
+		{
+			name:   "количество мест неизвестно",
+			ranked: []models.StudentEntry{entry("1", "270", "260")},
+			places: 0,
+			want:   CutOff{Status: models.CutOffUnknown},
+		},
+		{
+			name:   "на границе неразобранные баллы",
+			ranked: []models.StudentEntry{entry("1", "270", "260"), entry("2", "—", "")},
+			places: 2,
+			want:   CutOff{Status: models.CutOffUnknown},
+		},
+		{
+			name:   "на границе БВИ",
+			ranked: []models.StudentEntry{{UniqueCode: "1", BVIBasis: "Олимпиада"}, entry("2", "260", "250")},
+			places: 1,
+			want:   CutOff{Status: models.CutOffUnknown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CalculateCutOff(tt.ranked, tt.places); got != tt.want {
+				t.Errorf("проходной балл %+v, ожидался %+v", got, tt.want)
+			}
+		})
+	}
+}
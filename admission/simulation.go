@@ -0,0 +1,157 @@
+package admission
+
+import (
+	"bot/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Program конкурсная группа, участвующая в распределении
+type Program struct {
+	GroupID string
+	Places  int
+	Entries []models.StudentEntry
+}
+
+// Options настройки моделирования
+type Options struct {
+	RequireConsent bool   // Учитывать только поступающих с согласием на зачисление
+	AlwaysInclude  string // Уникальный код, который учитывается даже без согласия (сам пользователь)
+}
+
+// GroupOutcome результат распределения по одной конкурсной группе
+type GroupOutcome struct {
+	Places     int
+	Applicants int                   // Сколько поступающих участвовало в конкурсе группы
+	Admitted   []models.StudentEntry // Прошедшие в порядке ранжирования
+}
+
+// CutOff возвращает проходной балл группы по итогам распределения
+func (g GroupOutcome) CutOff() CutOff {
+	return CalculateCutOff(g.Admitted, g.Places)
+}
+
+// Outcome результат моделирования зачисления
+type Outcome struct {
+	Groups   map[string]GroupOutcome // groupID -> результат группы
+	Assigned map[string]string       // уникальный код -> groupID, куда поступающий проходит
+	Priority map[string]int          // уникальный код -> приоритет направления, куда он проходит
+}
+
+// choice заявление поступающего в одну конкурсную группу
+type choice struct {
+	program  int
+	priority int
+	rank     int // Место в ранжированном списке группы
+}
+
+// Simulate распределяет поступающих по конкурсным группам: каждый проходит на направление
+// с самым высоким приоритетом, где ему хватает места. Используется алгоритм отложенного
+// согласия: поступающий подает заявление в следующую по приоритету группу, группа оставляет
+// лучших по ранжированию (см. Rank) и отклоняет тех, кто не помещается в количество мест
+func Simulate(programs []Program, options Options) Outcome {
+	choices := make(map[string][]choice)
+	for i, program := range programs {
+		seen := make(map[string]bool)
+		for rank, entry := range Rank(program.Entries) {
+			code := strings.TrimSpace(entry.UniqueCode)
+			if code == "" || seen[code] {
+				continue
+			}
+			seen[code] = true
+			if options.RequireConsent && !entry.HasConsent() && code != options.AlwaysInclude {
+				continue
+			}
+			choices[code] = append(choices[code], choice{program: i, priority: parsePriority(entry.Priority), rank: rank})
+		}
+	}
+
+	// Сортируем заявления каждого поступающего по приоритету
+	codes := make([]string, 0, len(choices))
+	for code, list := range choices {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].priority < list[j].priority })
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	admitted := make([][]string, len(programs))
+	applicants := make([]int, len(programs))
+	next := make(map[string]int, len(codes))
+	current := make(map[string]choice, len(codes))
+
+	for _, code := range codes {
+		for _, c := range choices[code] {
+			applicants[c.program]++
+		}
+	}
+
+	queue := append([]string(nil), codes...)
+	for len(queue) > 0 {
+		code := queue[0]
+		queue = queue[1:]
+
+		list := choices[code]
+		if next[code] >= len(list) {
+			continue
+		}
+		c := list[next[code]]
+		next[code]++
+
+		program := programs[c.program]
+		if program.Places <= 0 {
+			queue = append(queue, code)
+			continue
+		}
+
+		admitted[c.program] = insertByRank(admitted[c.program], code, c.rank, current, c)
+		if len(admitted[c.program]) > program.Places {
+			// Группа переполнена - отклоняем худшего по списку, он пойдет на следующий приоритет
+			rejected := admitted[c.program][len(admitted[c.program])-1]
+			admitted[c.program] = admitted[c.program][:len(admitted[c.program])-1]
+			delete(current, rejected)
+			queue = append(queue, rejected)
+		}
+	}
+
+	outcome := Outcome{
+		Groups:   make(map[string]GroupOutcome, len(programs)),
+		Assigned: make(map[string]string),
+		Priority: make(map[string]int),
+	}
+	for i, program := range programs {
+		byCode := make(map[string]models.StudentEntry, len(program.Entries))
+		for _, entry := range program.Entries {
+			byCode[strings.TrimSpace(entry.UniqueCode)] = entry
+		}
+
+		group := GroupOutcome{Places: program.Places, Applicants: applicants[i]}
+		for _, code := range admitted[i] {
+			group.Admitted = append(group.Admitted, byCode[code])
+			outcome.Assigned[code] = program.GroupID
+			outcome.Priority[code] = current[code].priority
+		}
+		outcome.Groups[program.GroupID] = group
+	}
+	return outcome
+}
+
+// insertByRank добавляет поступающего в список прошедших, сохраняя порядок ранжирования
+func insertByRank(admitted []string, code string, rank int, current map[string]choice, c choice) []string {
+	current[code] = c
+	i := sort.Search(len(admitted), func(i int) bool { return current[admitted[i]].rank > rank })
+	admitted = append(admitted, "")
+	copy(admitted[i+1:], admitted[i:])
+	admitted[i] = code
+	return admitted
+}
+
+// parsePriority разбирает номер приоритета. Заявления без приоритета рассматриваются последними
+func parsePriority(value string) int {
+	priority, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || priority <= 0 {
+		return math.MaxInt32
+	}
+	return priority
+}
@@ -0,0 +1,94 @@
+package admission
+
+import (
+	"bot/models"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func application(code, total, priority, consent string) models.StudentEntry {
+	return models.StudentEntry{UniqueCode: code, TotalScore: total, Priority: priority, AdmissionConsent: consent}
+}
+
+func TestSimulate(t *testing.T) {
+	tests := []struct {
+		name     string
+		programs []Program
+		options  Options
+		assigned map[string]string   // уникальный код -> группа
+		admitted map[string][]string // группа -> прошедшие в порядке ранжирования
+	}{
+		{
+			name: "лучшие по ранжированию на количество мест",
+			programs: []Program{
+				{GroupID: "A", Places: 2, Entries: []models.StudentEntry{
+					application("1", "250", "1", ""), application("2", "270", "1", ""), application("3", "260", "1", ""),
+				}},
+			},
+			assigned: map[string]string{"2": "A", "3": "A"},
+			admitted: map[string][]string{"A": {"2", "3"}},
+		},
+		{
+			name: "проходит на высший приоритет и освобождает место",
+			programs: []Program{
+				{GroupID: "A", Places: 1, Entries: []models.StudentEntry{application("1", "270", "1", ""), application("2", "260", "2", "")}},
+				{GroupID: "B", Places: 1, Entries: []models.StudentEntry{application("1", "270", "2", ""), application("2", "260", "1", "")}},
+			},
+			assigned: map[string]string{"1": "A", "2": "B"},
+			admitted: map[string][]string{"A": {"1"}, "B": {"2"}},
+		},
+		{
+			name: "вытесненный переходит на следующий приоритет",
+			programs: []Program{
+				{GroupID: "A", Places: 1, Entries: []models.StudentEntry{application("1", "250", "2", ""), application("2", "270", "2", "")}},
+				{GroupID: "B", Places: 1, Entries: []models.StudentEntry{application("1", "250", "1", ""), application("2", "270", "1", "")}},
+			},
+			assigned: map[string]string{"1": "A", "2": "B"},
+			admitted: map[string][]string{"A": {"1"}, "B": {"2"}},
+		},
+		{
+			name: "заявление без приоритета рассматривается последним",
+			programs: []Program{
+				{GroupID: "A", Places: 1, Entries: []models.StudentEntry{application("1", "270", "", "")}},
+				{GroupID: "B", Places: 1, Entries: []models.StudentEntry{application("1", "270", "2", "")}},
+			},
+			assigned: map[string]string{"1": "B"},
+			admitted: map[string][]string{"A": nil, "B": {"1"}},
+		},
+		{
+			name: "группа без мест никого не принимает",
+			programs: []Program{
+				{GroupID: "A", Places: 0, Entries: []models.StudentEntry{application("1", "270", "1", "")}},
+				{GroupID: "B", Places: 1, Entries: []models.StudentEntry{application("1", "270", "2", "")}},
+			},
+			assigned: map[string]string{"1": "B"},
+			admitted: map[string][]string{"A": nil, "B": {"1"}},
+		},
+		{
+			name: "только с согласием, кроме самого пользователя",
+			programs: []Program{
+				{GroupID: "A", Places: 2, Entries: []models.StudentEntry{
+					application("1", "290", "1", ""), application("2", "250", "1", "✓"), application("3", "240", "1", ""), application("4", "230", "1", "да"),
+				}},
+			},
+			options:  Options{RequireConsent: true, AlwaysInclude: "3"},
+			assigned: map[string]string{"2": "A", "3": "A"},
+			admitted: map[string][]string{"A": {"2", "3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := Simulate(tt.programs, tt.options)
+			if !maps.Equal(outcome.Assigned, tt.assigned) {
+				t.Errorf("распределение %v, ожидалось %v", outcome.Assigned, tt.assigned)
+			}
+			for groupID, want := range tt.admitted {
+				if got := codes(outcome.Groups[groupID].Admitted); !slices.Equal(got, want) {
+					t.Errorf("прошли в %s: %v, ожидалось %v", groupID, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package analytics
+
+import (
+	"bot/admission"
+	"bot/models"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	// Window за какой период истории оценивается динамика позиции
+	Window = 7 * 24 * time.Hour
+	// horizon на сколько вперед продлевается наблюдаемая динамика позиции
+	horizon = 3 * 24 * time.Hour
+	// lateConsentShare какая доля стоящих выше без согласия, по нашей оценке, еще подаст его
+	lateConsentShare = 0.5
+	// minSpreadShare минимальная неопределенность позиции в долях от количества мест
+	minSpreadShare = 0.05
+)
+
+// Estimate оценка шанса поступающего на бюджетное место в конкурсной группе
+type Estimate struct {
+	Probability  float64 // Шанс от 0 до 1
+	Position     int     // Текущая позиция по общему конкурсу
+	Places       int     // Количество мест, за которые конкурирует поступающий
+	ExpectedRank float64 // Ожидаемая позиция с учетом согласий и динамики
+	ConsentRate  float64 // Доля подавших согласие среди стоящих выше
+	Trend        float64 // Изменение позиции за сутки по истории, положительное - вниз по списку
+}
+
+// RankedSnapshot версия списка, уже ранжированная по высшему проходному приоритету.
+// Историю ранжируют один раз и оценивают по ней шансы всех поступающих группы
+type RankedSnapshot struct {
+	CreatedAt    time.Time
+	BudgetPlaces int
+	Ranked       []models.StudentEntry
+}
+
+// RankHistory ранжирует версии списка группы
+func RankHistory(history []models.Snapshot) []RankedSnapshot {
+	ranked := make([]RankedSnapshot, len(history))
+	for i, snapshot := range history {
+		ranked[i] = RankedSnapshot{
+			CreatedAt:    snapshot.CreatedAt(),
+			BudgetPlaces: snapshot.BudgetPlaces,
+			Ranked:       admission.Rank(admission.HighPassingPriority(snapshot.Entries)),
+		}
+	}
+	return ranked
+}
+
+// Calculate оценивает шанс поступающего по ранжированным версиям списка группы в порядке формирования,
+// последняя версия - текущая. Второе значение false, если поступающего нет в текущей версии.
+//
+// Оценка складывается так: стоящие выше без согласия учитываются частично (часть из них
+// уйдет на другие направления), наблюдаемая за Window динамика позиции продлевается на horizon,
+// а разброс позиции за тот же период задает неопределенность
+func Calculate(history []RankedSnapshot, uniqueCode string) (Estimate, bool) {
+	if len(history) == 0 {
+		return Estimate{}, false
+	}
+	current := history[len(history)-1]
+
+	ranked := current.Ranked
+	rank, ok := findRank(ranked, uniqueCode)
+	if !ok {
+		return Estimate{}, false
+	}
+	position, places := admission.Position(ranked, rank, current.BudgetPlaces)
+	estimate := Estimate{Position: position, Places: places}
+	if places <= 0 {
+		return estimate, true
+	}
+
+	// Доля стоящих выше, которые действительно займут места. Для общего конкурса БВИ и ч. 10
+	// уже вычтены из количества мест, поэтому среди стоящих выше они не считаются
+	userFirst := ranked[rank-1].Category().TakesSeatFirst()
+	consentsStarted := false
+	above, consentAbove := 0, 0
+	for i, entry := range ranked {
+		if entry.HasConsent() {
+			consentsStarted = true
+		}
+		if i >= rank-1 || (!userFirst && entry.Category().TakesSeatFirst()) {
+			continue
+		}
+		above++
+		if entry.HasConsent() {
+			consentAbove++
+		}
+	}
+	share := 1.0
+	if above > 0 {
+		estimate.ConsentRate = float64(consentAbove) / float64(above)
+	}
+	if consentsStarted {
+		share = estimate.ConsentRate + (1-estimate.ConsentRate)*lateConsentShare
+	}
+
+	// Динамика и разброс позиции за последние Window
+	since := current.CreatedAt.Add(-Window)
+	var times []time.Time
+	var positions []float64
+	for _, snapshot := range history {
+		if snapshot.CreatedAt.Before(since) {
+			continue
+		}
+		rank, ok := findRank(snapshot.Ranked, uniqueCode)
+		if !ok {
+			continue
+		}
+		position, _ := admission.Position(snapshot.Ranked, rank, snapshot.BudgetPlaces)
+		times = append(times, snapshot.CreatedAt)
+		positions = append(positions, float64(position))
+	}
+	if n := len(positions); n >= 2 {
+		if days := times[n-1].Sub(times[0]).Hours() / 24; days > 0 {
+			estimate.Trend = (positions[n-1] - positions[0]) / days
+		}
+	}
+
+	estimate.ExpectedRank = math.Max(1, 1+float64(above)*share+estimate.Trend*horizon.Hours()/24)
+
+	spread := math.Max(math.Max(stddev(positions), float64(places)*minSpreadShare), 1)
+	// Логистическая функция с коэффициентом 1.7 близка к функции нормального распределения
+	z := (float64(places) + 0.5 - estimate.ExpectedRank) / spread
+	estimate.Probability = math.Min(0.99, math.Max(0.01, 1/(1+math.Exp(-1.7*z))))
+
+	return estimate, true
+}
+
+// findRank возвращает место (с 1) поступающего в ранжированном списке
+func findRank(ranked []models.StudentEntry, uniqueCode string) (int, bool) {
+	for i, entry := range ranked {
+		if strings.TrimSpace(entry.UniqueCode) == uniqueCode {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(squares / float64(len(values)-1))
+}
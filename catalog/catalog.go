@@ -0,0 +1,139 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Group описывает конкурсную группу (направление) и адрес ее конкурсного списка
+type Group struct {
+	ID      string `json:"id"`               // Идентификатор группы на сайте, например "000000012"
+	Code    string `json:"code"`             // Код направления, например "09.03.02"
+	Name    string `json:"name"`             // Название образовательной программы
+	Form    string `json:"form"`             // Форма обучения
+	Funding string `json:"funding"`          // Основа обучения
+	Quota   string `json:"quota"`            // Вид конкурса (общий конкурс, целевая квота и т.д.)
+	URL     string `json:"url"`              // Адрес страницы конкурсного списка
+	Source  string `json:"source,omitempty"` // Источник списка (вуз), пустое значение - МГСУ
+}
+
+// Title возвращает название группы для показа пользователю
+func (g Group) Title() string {
+	parts := []string{strings.TrimSpace(g.Code + " " + g.Name)}
+	if g.Form != "" {
+		parts = append(parts, g.Form)
+	}
+	if g.Funding != "" {
+		parts = append(parts, g.Funding)
+	}
+	if g.Quota != "" {
+		parts = append(parts, g.Quota)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Default возвращает встроенный список конкурсных групп
+func Default() []Group {
+	return []Group{
+		{
+			ID:      "000000012",
+			Code:    "09.03.02",
+			Name:    "Информационные системы и технологии",
+			Form:    "очная",
+			Funding: "бюджет",
+			Quota:   "общий конкурс",
+			URL:     "https://mgsu.ru/2025/ks/bs/list.php?p=000000012_09.03.02_Informatsionnye_sistemy_i_tekhnologii_Ochnaya_Byudzhet_Obshchiy%20konkurs.html",
+		},
+	}
+}
+
+// LoadFile читает список конкурсных групп из JSON-файла
+func LoadFile(path string) ([]Group, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Group
+	if err := json.Unmarshal(data, &groups); err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога %s: %v", path, err)
+	}
+
+	for _, group := range groups {
+		if group.ID == "" || group.URL == "" {
+			return nil, fmt.Errorf("в каталоге %s есть группа без id или url", path)
+		}
+	}
+
+	return groups, nil
+}
+
+// Catalog потокобезопасный каталог конкурсных групп
+type Catalog struct {
+	mutex  sync.RWMutex
+	groups map[string]Group
+}
+
+func New(groups []Group) *Catalog {
+	c := &Catalog{}
+	c.Replace(groups)
+	return c
+}
+
+// Replace заменяет содержимое каталога
+func (c *Catalog) Replace(groups []Group) {
+	byID := make(map[string]Group, len(groups))
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+
+	c.mutex.Lock()
+	c.groups = byID
+	c.mutex.Unlock()
+}
+
+// ReplaceSource заменяет только группы указанного источника, группы других вузов сохраняются
+func (c *Catalog) ReplaceSource(source string, groups []Group) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for id, group := range c.groups {
+		if group.Source == source {
+			delete(c.groups, id)
+		}
+	}
+	for _, group := range groups {
+		group.Source = source
+		c.groups[group.ID] = group
+	}
+}
+
+// Get возвращает группу по идентификатору
+func (c *Catalog) Get(id string) (Group, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	group, ok := c.groups[id]
+	return group, ok
+}
+
+// All возвращает все группы, отсортированные по коду направления и идентификатору
+func (c *Catalog) All() []Group {
+	c.mutex.RLock()
+	groups := make([]Group, 0, len(c.groups))
+	for _, group := range c.groups {
+		groups = append(groups, group)
+	}
+	c.mutex.RUnlock()
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Code != groups[j].Code {
+			return groups[i].Code < groups[j].Code
+		}
+		return groups[i].ID < groups[j].ID
+	})
+	return groups
+}
@@ -0,0 +1,172 @@
+package catalog
+
+import (
+	"bot/fetcher"
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// directionCodePattern код направления вида 09.03.02
+var directionCodePattern = regexp.MustCompile(`^\d{2}\.\d{2}\.\d{2}$`)
+
+// cyrillicPattern проверяет, что в тексте ссылки есть русские буквы
+var cyrillicPattern = regexp.MustCompile(`[А-Яа-яЁё]`)
+
+// Транслитерированные значения из имени файла списка и их русские названия
+var (
+	studyForms = map[string]string{
+		"Ochnaya":         "очная",
+		"Zaochnaya":       "заочная",
+		"Ochno-zaochnaya": "очно-заочная",
+		"Ochno-Zaochnaya": "очно-заочная",
+	}
+	fundingTypes = map[string]string{
+		"Byudzhet": "бюджет",
+		"Platnoe":  "платное",
+		"Dogovor":  "договор",
+	}
+	quotaTypes = map[string]string{
+		"Obshchiy konkurs": "общий конкурс",
+		"Otdelnaya kvota":  "отдельная квота",
+		"Osobaya kvota":    "особая квота",
+		"Tselevaya kvota":  "целевая квота",
+	}
+)
+
+// Crawler находит страницы конкурсных списков на индексной странице приемной комиссии
+type Crawler struct {
+	IndexURL string
+	fetcher  *fetcher.Fetcher
+}
+
+func NewCrawler(indexURL string, pageFetcher *fetcher.Fetcher) *Crawler {
+	return &Crawler{IndexURL: indexURL, fetcher: pageFetcher}
+}
+
+// Crawl загружает индексную страницу и возвращает все найденные конкурсные группы
+func (c *Crawler) Crawl() ([]Group, error) {
+	base, err := url.Parse(c.IndexURL)
+	if err != nil {
+		return nil, fmt.Errorf("некорректный адрес индекса: %v", err)
+	}
+
+	resp, err := c.fetcher.Get(context.Background(), c.IndexURL)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки индекса: %v", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга индекса: %v", err)
+	}
+
+	var groups []Group
+	seen := make(map[string]bool)
+	doc.Find(`a[href*="list.php"]`).Each(func(i int, link *goquery.Selection) {
+		href, _ := link.Attr("href")
+		ref, err := url.Parse(strings.TrimSpace(href))
+		if err != nil {
+			return
+		}
+		listURL := base.ResolveReference(ref)
+
+		group, ok := ParseSlug(listURL.Query().Get("p"))
+		if !ok || seen[group.ID] {
+			return
+		}
+		seen[group.ID] = true
+
+		// Текст ссылки обычно содержит название программы по-русски, в отличие от транслита в имени файла
+		if text := strings.Join(strings.Fields(link.Text()), " "); cyrillicPattern.MatchString(text) {
+			group.Name = strings.TrimSpace(strings.TrimPrefix(text, group.Code))
+		}
+		group.URL = listURL.String()
+		groups = append(groups, group)
+	})
+
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("на странице %s не найдено ни одного конкурсного списка", c.IndexURL)
+	}
+
+	return groups, nil
+}
+
+// ParseSlug разбирает параметр p= страницы списка, например
+// "000000012_09.03.02_Informatsionnye_sistemy_i_tekhnologii_Ochnaya_Byudzhet_Obshchiy konkurs.html"
+func ParseSlug(slug string) (Group, bool) {
+	slug = strings.TrimSuffix(strings.TrimSpace(slug), ".html")
+	parts := strings.Split(slug, "_")
+	if len(parts) < 3 || parts[0] == "" || !directionCodePattern.MatchString(parts[1]) {
+		return Group{}, false
+	}
+
+	group := Group{ID: parts[0], Code: parts[1]}
+	rest := parts[2:]
+
+	// Название программы идет до формы обучения, после нее - основа обучения и вид конкурса
+	formIndex := -1
+	for i, part := range rest {
+		if _, ok := studyForms[part]; ok {
+			formIndex = i
+			break
+		}
+	}
+	if formIndex == -1 {
+		group.Name = strings.Join(rest, " ")
+		return group, true
+	}
+
+	group.Name = strings.Join(rest[:formIndex], " ")
+	group.Form = studyForms[rest[formIndex]]
+	if formIndex+1 < len(rest) {
+		group.Funding = translate(fundingTypes, rest[formIndex+1])
+	}
+	if formIndex+2 < len(rest) {
+		group.Quota = translate(quotaTypes, strings.Join(rest[formIndex+2:], " "))
+	}
+
+	return group, true
+}
+
+// Start периодически обновляет каталог результатами обхода индекса.
+// save вызывается после каждого успешного обновления, например для сохранения в хранилище
+func (c *Crawler) Start(target *Catalog, interval time.Duration, save func([]Group) error) {
+	go func() {
+		for {
+			c.refresh(target, save)
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (c *Crawler) refresh(target *Catalog, save func([]Group) error) {
+	groups, err := c.Crawl()
+	if err != nil {
+		fmt.Printf("Ошибка обновления каталога конкурсных групп: %v\n", err)
+		return
+	}
+
+	// Обход индекса находит только группы МГСУ, группы других вузов из каталога не трогаем
+	target.ReplaceSource("", groups)
+	fmt.Printf("Каталог конкурсных групп обновлен: %d групп\n", len(groups))
+
+	if save != nil {
+		if err := save(target.All()); err != nil {
+			fmt.Printf("Ошибка сохранения каталога конкурсных групп: %v\n", err)
+		}
+	}
+}
+
+func translate(dictionary map[string]string, value string) string {
+	if translated, ok := dictionary[value]; ok {
+		return translated
+	}
+	return value
+}
@@ -0,0 +1,56 @@
+package catalog
+
+import "testing"
+
+func TestParseSlug(t *testing.T) {
+	tests := []struct {
+		name string
+		slug string
+		want Group
+		ok   bool
+	}{
+		{
+			name: "полное имя файла",
+			slug: "000000012_09.03.02_Informatsionnye_sistemy_i_tekhnologii_Ochnaya_Byudzhet_Obshchiy konkurs.html",
+			want: Group{ID: "000000012", Code: "09.03.02", Name: "Informatsionnye sistemy i tekhnologii", Form: "очная", Funding: "бюджет", Quota: "общий конкурс"},
+			ok:   true,
+		},
+		{
+			name: "очно-заочная форма и целевая квота",
+			slug: "000000031_08.03.01_Stroitelstvo_Ochno-zaochnaya_Byudzhet_Tselevaya kvota",
+			want: Group{ID: "000000031", Code: "08.03.01", Name: "Stroitelstvo", Form: "очно-заочная", Funding: "бюджет", Quota: "целевая квота"},
+			ok:   true,
+		},
+		{
+			name: "неизвестные основа и вид конкурса остаются как есть",
+			slug: "000000040_08.04.01_Stroitelstvo_Zaochnaya_Grant_Novyy_konkurs.html",
+			want: Group{ID: "000000040", Code: "08.04.01", Name: "Stroitelstvo", Form: "заочная", Funding: "Grant", Quota: "Novyy konkurs"},
+			ok:   true,
+		},
+		{
+			name: "без основы обучения",
+			slug: "000000041_08.03.01_Stroitelstvo_Ochnaya",
+			want: Group{ID: "000000041", Code: "08.03.01", Name: "Stroitelstvo", Form: "очная"},
+			ok:   true,
+		},
+		{
+			name: "без формы обучения все остальное - название",
+			slug: "000000050_08.03.01_Stroitelstvo_unikalnykh_zdaniy",
+			want: Group{ID: "000000050", Code: "08.03.01", Name: "Stroitelstvo unikalnykh zdaniy"},
+			ok:   true,
+		},
+		{name: "неверный код направления", slug: "000000012_09-03-02_Stroitelstvo_Ochnaya.html"},
+		{name: "нет идентификатора", slug: "_09.03.02_Stroitelstvo_Ochnaya.html"},
+		{name: "слишком мало частей", slug: "000000012_09.03.02.html"},
+		{name: "пустая строка", slug: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseSlug(tt.slug)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseSlug(%q) = %+v, %v, ожидалось %+v, %v", tt.slug, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package charts
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Встроенный шрифт поддерживает только ASCII, поэтому подписи на графике - латиница и цифры,
+// а русские пояснения передаются в подписи к фотографии
+var (
+	Blue  = color.RGBA{R: 0x1f, G: 0x77, B: 0xb4, A: 0xff}
+	Red   = color.RGBA{R: 0xd6, G: 0x27, B: 0x28, A: 0xff}
+	Green = color.RGBA{R: 0x2c, G: 0xa0, B: 0x2c, A: 0xff}
+
+	background = color.White
+	axisColor  = color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	gridColor  = color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+)
+
+const (
+	panelWidth   = 800
+	panelHeight  = 300
+	marginLeft   = 60
+	marginRight  = 20
+	marginTop    = 30
+	marginBottom = 30
+	yTicks       = 5
+	maxXTicks    = 7
+)
+
+// Point значение ряда в момент времени
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Series линия на графике
+type Series struct {
+	Points []Point
+	Color  color.Color
+}
+
+// Panel отдельный график с общей осью времени
+type Panel struct {
+	Title   string // Заголовок (только ASCII)
+	Series  []Series
+	InvertY bool // Меньшие значения сверху, удобно для позиции в списке
+}
+
+// RenderPNG рисует панели друг под другом и записывает изображение в формате PNG
+func RenderPNG(w io.Writer, panels ...Panel) error {
+	if len(panels) == 0 {
+		return fmt.Errorf("нет данных для графика")
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, panelWidth, panelHeight*len(panels)))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	for i, panel := range panels {
+		drawPanel(img, image.Rect(0, i*panelHeight, panelWidth, (i+1)*panelHeight), panel)
+	}
+
+	return png.Encode(w, img)
+}
+
+func drawPanel(img *image.RGBA, bounds image.Rectangle, panel Panel) {
+	plot := image.Rect(
+		bounds.Min.X+marginLeft,
+		bounds.Min.Y+marginTop,
+		bounds.Max.X-marginRight,
+		bounds.Max.Y-marginBottom,
+	)
+
+	drawText(img, bounds.Min.X+marginLeft, bounds.Min.Y+marginTop-10, panel.Title, axisColor)
+
+	minTime, maxTime, minValue, maxValue, ok := dataRange(panel.Series)
+	if !ok {
+		drawText(img, plot.Min.X+10, plot.Min.Y+20, "no data", axisColor)
+		return
+	}
+
+	// Небольшой запас сверху и снизу, чтобы линия не прилипала к краям
+	padding := (maxValue - minValue) * 0.1
+	if padding == 0 {
+		padding = 1
+	}
+	minValue -= padding
+	maxValue += padding
+
+	toX := func(t time.Time) int {
+		if maxTime.Equal(minTime) {
+			return (plot.Min.X + plot.Max.X) / 2
+		}
+		ratio := float64(t.Sub(minTime)) / float64(maxTime.Sub(minTime))
+		return plot.Min.X + int(ratio*float64(plot.Dx()))
+	}
+	toY := func(v float64) int {
+		ratio := (v - minValue) / (maxValue - minValue)
+		if panel.InvertY {
+			return plot.Min.Y + int(ratio*float64(plot.Dy()))
+		}
+		return plot.Max.Y - int(ratio*float64(plot.Dy()))
+	}
+
+	// Сетка и подписи оси значений
+	for i := 0; i <= yTicks; i++ {
+		value := minValue + (maxValue-minValue)*float64(i)/yTicks
+		y := toY(value)
+		drawLine(img, plot.Min.X, y, plot.Max.X, y, gridColor, 1)
+		drawText(img, bounds.Min.X+5, y+4, fmt.Sprintf("%.0f", value), axisColor)
+	}
+
+	// Подписи оси времени
+	ticks := timeTicks(panel.Series)
+	for _, t := range ticks {
+		x := toX(t)
+		drawLine(img, x, plot.Min.Y, x, plot.Max.Y, gridColor, 1)
+		drawText(img, x-17, plot.Max.Y+18, t.Format("02.01"), axisColor)
+	}
+
+	drawLine(img, plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y, axisColor, 1)
+	drawLine(img, plot.Min.X, plot.Min.Y, plot.Min.X, plot.Max.Y, axisColor, 1)
+
+	for _, series := range panel.Series {
+		for i, point := range series.Points {
+			x, y := toX(point.Time), toY(point.Value)
+			if i > 0 {
+				prev := series.Points[i-1]
+				drawLine(img, toX(prev.Time), toY(prev.Value), x, y, series.Color, 2)
+			}
+			fillSquare(img, x, y, 3, series.Color)
+		}
+	}
+}
+
+func dataRange(series []Series) (minTime, maxTime time.Time, minValue, maxValue float64, ok bool) {
+	minValue, maxValue = math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, point := range s.Points {
+			if !ok || point.Time.Before(minTime) {
+				minTime = point.Time
+			}
+			if !ok || point.Time.After(maxTime) {
+				maxTime = point.Time
+			}
+			minValue = math.Min(minValue, point.Value)
+			maxValue = math.Max(maxValue, point.Value)
+			ok = true
+		}
+	}
+	return
+}
+
+// timeTicks выбирает не более maxXTicks равномерно распределенных моментов из данных
+func timeTicks(series []Series) []time.Time {
+	seen := make(map[int64]bool)
+	var times []time.Time
+	for _, s := range series {
+		for _, point := range s.Points {
+			if !seen[point.Time.Unix()] {
+				seen[point.Time.Unix()] = true
+				times = append(times, point.Time)
+			}
+		}
+	}
+	if len(times) <= maxXTicks {
+		return times
+	}
+
+	step := float64(len(times)-1) / float64(maxXTicks-1)
+	ticks := make([]time.Time, 0, maxXTicks)
+	for i := 0; i < maxXTicks; i++ {
+		ticks = append(ticks, times[int(math.Round(float64(i)*step))])
+	}
+	return ticks
+}
+
+// drawLine рисует отрезок алгоритмом Брезенхэма заданной толщины
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color, width int) {
+	dx := abs(x1 - x0)
+	dy := -abs(y1 - y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	e := dx + dy
+
+	for {
+		fillSquare(img, x0, y0, width/2, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y0 += sy
+		}
+	}
+}
+
+func fillSquare(img *image.RGBA, x, y, radius int, c color.Color) {
+	for i := -radius; i <= radius; i++ {
+		for j := -radius; j <= radius; j++ {
+			img.Set(x+i, y+j, c)
+		}
+	}
+}
+
+func drawText(img *image.RGBA, x, y int, text string, c color.Color) {
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: basicfont.Face7x13,
+		Dot:  fixed.P(x, y),
+	}
+	drawer.DrawString(text)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -1,9 +1,67 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 var (
-	TG_TOKEN = getEnv("TG_TOKEN", "1")
+	TG_TOKEN    = getEnv("TG_TOKEN", "1")
+	DB_PATH     = getEnv("DB_PATH", "data/bot.db")
+	GROUPS_PATH = getEnv("GROUPS_PATH", "data/groups.json")
+
+	// Способ получения обновлений: "polling" (long polling) или "webhook"
+	BOT_MODE = getEnv("BOT_MODE", "polling")
+
+	// Вебхук: публичный адрес для Telegram, адрес и путь локального HTTP-сервера и секрет,
+	// который Telegram присылает в заголовке X-Telegram-Bot-Api-Secret-Token
+	WEBHOOK_URL         = getEnv("WEBHOOK_URL", "")
+	WEBHOOK_LISTEN_ADDR = getEnv("WEBHOOK_LISTEN_ADDR", ":8080")
+	WEBHOOK_PATH        = getEnv("WEBHOOK_PATH", "/telegram")
+	WEBHOOK_SECRET      = getEnv("WEBHOOK_SECRET", "")
+
+	// Индекс конкурсных списков, с которого обновляется каталог групп (пустое значение отключает обход)
+	CATALOG_INDEX_URL        = getEnv("CATALOG_INDEX_URL", "https://mgsu.ru/2025/ks/bs/")
+	CATALOG_REFRESH_INTERVAL = getDurationEnv("CATALOG_REFRESH_INTERVAL", 6*time.Hour)
+
+	// Хранение версий конкурсных списков: максимальный возраст и количество на группу (0 - без ограничения)
+	SNAPSHOT_RETENTION     = getDurationEnv("SNAPSHOT_RETENTION", 180*24*time.Hour)
+	SNAPSHOT_MAX_PER_GROUP = getIntEnv("SNAPSHOT_MAX_PER_GROUP", 0)
+
+	// Чаты администраторов через запятую, им приходят предупреждения об изменении страниц МГСУ
+	ADMIN_IDS = getInt64ListEnv("ADMIN_IDS")
+
+	// Доступ к боту: если ALLOWED_USER_IDS не пуст, бот отвечает только этим пользователям.
+	// Пользователям из BLOCKED_USER_IDS бот не отвечает совсем
+	ALLOWED_USER_IDS = getInt64ListEnv("ALLOWED_USER_IDS")
+	BLOCKED_USER_IDS = getInt64ListEnv("BLOCKED_USER_IDS")
+
+	// Не более RATE_LIMIT обновлений от пользователя за RATE_LIMIT_PERIOD (0 отключает ограничение)
+	RATE_LIMIT        = getIntEnv("RATE_LIMIT", 20)
+	RATE_LIMIT_PERIOD = getDurationEnv("RATE_LIMIT_PERIOD", time.Minute)
+
+	// Обновления, обработка которых дольше порога, записываются в лог
+	SLOW_UPDATE_THRESHOLD = getDurationEnv("SLOW_UPDATE_THRESHOLD", 2*time.Second)
+
+	// Адрес HTTP-сервера с метриками для Prometheus (пустое значение отключает метрики)
+	METRICS_ADDR = getEnv("METRICS_ADDR", "")
+
+	// Сколько переиспользовать загруженную страницу списка для ответов пользователям
+	LIST_CACHE_TTL = getDurationEnv("LIST_CACHE_TTL", time.Minute)
+
+	// Сколько переиспользовать списки остальных направлений при моделировании зачисления:
+	// для прогноза загружаются все бюджетные списки вуза, а не только выбранные пользователем
+	PREDICTION_LIST_TTL = getDurationEnv("PREDICTION_LIST_TTL", 30*time.Minute)
+
+	// Загрузка страниц МГСУ
+	HTTP_TIMEOUT     = getDurationEnv("HTTP_TIMEOUT", 30*time.Second)
+	HTTP_MAX_RETRIES = getIntEnv("HTTP_MAX_RETRIES", 3)
+	HTTP_BACKOFF     = getDurationEnv("HTTP_BACKOFF", time.Second)
+	HTTP_MAX_BACKOFF = getDurationEnv("HTTP_MAX_BACKOFF", 30*time.Second)
+	HTTP_USER_AGENT  = getEnv("HTTP_USER_AGENT", "Mozilla/5.0 (compatible; mgsu_bot/1.0)")
+	HTTP_PROXY_URL   = getEnv("HTTP_PROXY_URL", "")
 )
 
 func getEnv(key, defaultValue string) string {
@@ -12,3 +70,31 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
+func getIntEnv(key string, defaultValue int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
+	}
+	return defaultValue
+}
+
+func getInt64ListEnv(key string) []int64 {
+	var result []int64
+	for _, part := range strings.Split(os.Getenv(key), ",") {
+		if number, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64); err == nil {
+			result = append(result, number)
+		}
+	}
+	return result
+}
@@ -0,0 +1,203 @@
+package fetcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxBodySize ограничение на размер страницы, чтобы ошибка сервера не съела всю память
+const maxBodySize = 32 << 20
+
+// Config настройки загрузки страниц
+type Config struct {
+	Timeout     time.Duration // Дедлайн одной попытки
+	MaxRetries  int           // Сколько раз повторять после первой неудачной попытки
+	BaseBackoff time.Duration // Начальная пауза между попытками, удваивается с каждой попыткой
+	MaxBackoff  time.Duration
+	UserAgent   string
+	ProxyURL    string // Пустое значение - без прокси
+}
+
+// Validators значения для условного запроса, полученные из предыдущего ответа
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// Response загруженная страница
+type Response struct {
+	URL         string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	Hash        string // SHA-256 тела ответа
+	NotModified bool   // Сервер ответил 304, тело пустое
+}
+
+// Validators возвращает ETag и Last-Modified ответа для следующего условного запроса
+func (r *Response) Validators() Validators {
+	return Validators{
+		ETag:         r.Header.Get("ETag"),
+		LastModified: r.Header.Get("Last-Modified"),
+	}
+}
+
+// StatusError сервер ответил кодом, отличным от 200
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("сервер вернул статус %d для %s", e.StatusCode, e.URL)
+}
+
+// Fetcher загружает страницы с таймаутами, повторами с экспоненциальной паузой и случайным разбросом
+type Fetcher struct {
+	client *http.Client
+	config Config
+}
+
+func New(config Config) (*Fetcher, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("некорректный адрес прокси: %v", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return &Fetcher{
+		client: &http.Client{Transport: transport},
+		config: config,
+	}, nil
+}
+
+// Get загружает страницу. Сетевые ошибки, 5xx и 429 повторяются, остальные статусы
+// возвращаются сразу как *StatusError
+func (f *Fetcher) Get(ctx context.Context, pageURL string) (*Response, error) {
+	return f.GetConditional(ctx, pageURL, Validators{})
+}
+
+// GetConditional загружает страницу с If-None-Match / If-Modified-Since, если они известны.
+// Если страница не изменилась, возвращается ответ с NotModified = true
+func (f *Fetcher) GetConditional(ctx context.Context, pageURL string, validators Validators) (*Response, error) {
+	var lastErr error
+	for attempt := 0; attempt <= f.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, f.backoff(attempt)); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := f.do(ctx, pageURL, validators)
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+
+		if !retryable(err) || ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("Попытка %d загрузки %s не удалась: %v\n", attempt+1, pageURL, err)
+	}
+	return nil, lastErr
+}
+
+func (f *Fetcher) do(ctx context.Context, pageURL string, validators Validators) (*Response, error) {
+	if f.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.config.UserAgent != "" {
+		req.Header.Set("User-Agent", f.config.UserAgent)
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return &Response{
+			URL:         pageURL,
+			StatusCode:  resp.StatusCode,
+			Header:      resp.Header,
+			NotModified: true,
+		}, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// Тело страницы с ошибкой не разбираем, но дочитываем, чтобы соединение можно было переиспользовать
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxBodySize))
+		return nil, &StatusError{URL: pageURL, StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(body)
+	return &Response{
+		URL:        pageURL,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		Hash:       hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// backoff возвращает паузу перед попыткой: BaseBackoff * 2^(attempt-1), не больше MaxBackoff,
+// со случайным разбросом, чтобы повторы разных запросов не совпадали
+func (f *Fetcher) backoff(attempt int) time.Duration {
+	delay := f.config.BaseBackoff << (attempt - 1)
+	if delay <= 0 || (f.config.MaxBackoff > 0 && delay > f.config.MaxBackoff) {
+		delay = f.config.MaxBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
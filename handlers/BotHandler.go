@@ -1,67 +1,185 @@
 package handlers
 
 import (
-	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// Приоритеты маршрутов: более конкретные правила проверяются раньше общих
+const (
+	PriorityHigh   = 100 // Команды и нажатия inline-кнопок
+	PriorityNormal = 50  // Тексты кнопок клавиатуры
+	PriorityLow    = 10  // Общие правила: регулярные выражения, ожидание ввода
+)
+
+// UpdateFunc обрабатывает обновление, выбранное маршрутом
+type UpdateFunc func(*tgbotapi.Update)
+
+// Matcher решает, подходит ли обновление маршруту
+type Matcher func(*tgbotapi.Update) bool
+
+type route struct {
+	priority int
+	match    Matcher
+	handle   UpdateFunc
+}
+
 type BotHandler struct {
-	bot      tgbotapi.BotAPI
-	updates  tgbotapi.UpdatesChannel
-	handlers []func(*tgbotapi.Update) bool
+	bot         tgbotapi.BotAPI
+	updates     tgbotapi.UpdatesChannel
+	routes      []route
+	fallback    UpdateFunc
+	middlewares []Middleware
+	delivery    *delivery // Общий для копий, которые хранят обработчики
+	outbox      *outbox   // Общая очередь исходящих запросов
+	lane        int       // Очередь, в которую эта копия ставит запросы
 }
 
 func NewBotHandler(updates *tgbotapi.UpdatesChannel, bot *tgbotapi.BotAPI) BotHandler {
-	return BotHandler{
+	b := BotHandler{
 		bot:      *bot,
 		updates:  *updates,
-		handlers: []func(*tgbotapi.Update) bool{},
+		delivery: &delivery{},
+		lane:     laneInteractive,
 	}
+	b.outbox = newOutbox(b.deliver)
+	return b
 }
 
-func (b *BotHandler) AddHandler(handler func(*tgbotapi.Update) bool) {
-	b.handlers = append(b.handlers, handler)
+// Broadcast возвращает копию, которая отправляет сообщения в очередь массовых уведомлений:
+// они уходят только тогда, когда нет ожидающих ответов пользователям
+func (b BotHandler) Broadcast() *BotHandler {
+	b.lane = laneBroadcast
+	return &b
 }
 
+// Handle регистрирует маршрут. Маршруты проверяются по убыванию приоритета,
+// при равном приоритете - в порядке регистрации; обновление обрабатывает первый подошедший
+func (b *BotHandler) Handle(priority int, match Matcher, handler UpdateFunc) {
+	b.routes = append(b.routes, route{priority: priority, match: match, handle: handler})
+	sort.SliceStable(b.routes, func(i, j int) bool { return b.routes[i].priority > b.routes[j].priority })
+}
+
+// SetFallback задает обработчик сообщений, которым не подошел ни один маршрут
+func (b *BotHandler) SetFallback(handler UpdateFunc) {
+	b.fallback = handler
+}
+
+// MessagesHandler обрабатывает обновления разных чатов параллельно, а обновления одного чата - по очереди
+// в порядке поступления. Так ожидание отправки в один чат не задерживает ответы остальным
 func (b *BotHandler) MessagesHandler() {
-	for update := range b.updates {
-		if update.Message != nil {
-			for _, handler := range b.handlers {
-				if handler(&update) {
-					break
-				}
+	handler := b.pipeline()
+
+	var mutex sync.Mutex
+	pending := make(map[int64][]*tgbotapi.Update) // chatID -> необработанные обновления; есть ключ - чат обрабатывается
+	process := func(chatID int64) {
+		for {
+			mutex.Lock()
+			queue := pending[chatID]
+			if len(queue) == 0 {
+				delete(pending, chatID)
+				mutex.Unlock()
+				return
 			}
-			log.Printf("[%s] %s", update.Message.From.UserName, update.Message.Text)
+			pending[chatID] = queue[1:]
+			mutex.Unlock()
+
+			handler(queue[0])
 		}
-		if update.CallbackQuery != nil {
-			for _, handler := range b.handlers {
-				if handler(&update) {
-					break
-				}
-			}
-			log.Printf("[%s] %s", update.CallbackQuery.From.UserName, update.CallbackQuery.Data)
+	}
+
+	for update := range b.updates {
+		chatID := updateChatID(&update)
+
+		mutex.Lock()
+		queue, running := pending[chatID]
+		pending[chatID] = append(queue, &update)
+		mutex.Unlock()
+
+		if !running {
+			go process(chatID)
 		}
 	}
 }
 
-func (b *BotHandler) SendTextMessage(chatID int64, text string) {
-	msg := tgbotapi.NewMessage(chatID, text)
-	if _, err := b.bot.Send(msg); err != nil {
-		log.Panic(err)
+// updateChatID возвращает чат обновления или 0, если обновление не относится к чату
+func updateChatID(update *tgbotapi.Update) int64 {
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
 	}
+	return 0
 }
 
-func (b *BotHandler) SendTextMessageWithMarkup(chatID int64, text string, replyMarkup tgbotapi.InlineKeyboardMarkup) {
+func (b *BotHandler) dispatch(update *tgbotapi.Update) {
+	for _, route := range b.routes {
+		if route.match(update) {
+			route.handle(update)
+			return
+		}
+	}
+	if update.Message != nil && b.fallback != nil {
+		b.fallback(update)
+	}
+}
+
+// MatchCommand подходит для команды /name
+func MatchCommand(name string) Matcher {
+	return func(update *tgbotapi.Update) bool {
+		return update.Message != nil && update.Message.IsCommand() && update.Message.Command() == name
+	}
+}
+
+// MatchText подходит для сообщения, текст которого в точности равен text (например, кнопки клавиатуры)
+func MatchText(text string) Matcher {
+	return func(update *tgbotapi.Update) bool {
+		return update.Message != nil && !update.Message.IsCommand() && update.Message.Text == text
+	}
+}
+
+// MatchRegexp подходит для сообщения, текст которого соответствует pattern
+func MatchRegexp(pattern *regexp.Regexp) Matcher {
+	return func(update *tgbotapi.Update) bool {
+		return update.Message != nil && !update.Message.IsCommand() && pattern.MatchString(update.Message.Text)
+	}
+}
+
+// MatchCallbackPrefix подходит для нажатия на inline-кнопку, данные которой начинаются с prefix
+func MatchCallbackPrefix(prefix string) Matcher {
+	return func(update *tgbotapi.Update) bool {
+		return update.CallbackQuery != nil && strings.HasPrefix(update.CallbackQuery.Data, prefix)
+	}
+}
+
+// OnMessage приводит обработчик сообщения к UpdateFunc
+func OnMessage(handler func(*tgbotapi.Message)) UpdateFunc {
+	return func(update *tgbotapi.Update) {
+		handler(update.Message)
+	}
+}
+
+// send ставит запрос в очередь исходящих и ждет результата отправки
+func (b *BotHandler) send(chatID int64, request tgbotapi.Chattable) error {
+	return b.outbox.enqueue(b.lane, chatID, request)
+}
+
+func (b *BotHandler) SendTextMessage(chatID int64, text string) error {
+	return b.send(chatID, tgbotapi.NewMessage(chatID, text))
+}
+
+func (b *BotHandler) SendTextMessageWithMarkup(chatID int64, text string, replyMarkup tgbotapi.InlineKeyboardMarkup) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = replyMarkup
-	b.bot.Send(msg)
+	return b.send(chatID, msg)
 }
 
-func (b *BotHandler) SendTextMessageWithKeyboardMarkup(chatID int64, text string, replyMarkup tgbotapi.ReplyKeyboardMarkup) {
+func (b *BotHandler) SendTextMessageWithKeyboardMarkup(chatID int64, text string, replyMarkup tgbotapi.ReplyKeyboardMarkup) error {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ReplyMarkup = replyMarkup
-	b.bot.Send(msg)
+	return b.send(chatID, msg)
 }
 
 func (b *BotHandler) SetKeyboardButtons(buttons []string, coloumsCount int) tgbotapi.ReplyKeyboardMarkup {
@@ -99,10 +217,20 @@ func (b *BotHandler) SetInlineKeybordButtons(buttons map[string]string, columnsC
 	return tgbotapi.NewInlineKeyboardMarkup(keyboard...)
 }
 
-func (b *BotHandler) SendTextMessageWithImage(chatID int64, text string, imagePath string) {
+func (b *BotHandler) SendTextMessageWithImage(chatID int64, text string, imagePath string) error {
 	msg := tgbotapi.NewPhoto(chatID, tgbotapi.FilePath(imagePath))
 	msg.Caption = text
-	if _, err := b.bot.Send(msg); err != nil {
-		log.Panic(err)
-	}
+	return b.send(chatID, msg)
+}
+
+func (b *BotHandler) EditMessageReplyMarkup(chatID int64, messageID int, replyMarkup tgbotapi.InlineKeyboardMarkup) error {
+	return b.send(chatID, tgbotapi.NewEditMessageReplyMarkup(chatID, messageID, replyMarkup))
+}
+
+func (b *BotHandler) EditMessageTextWithMarkup(chatID int64, messageID int, text string, replyMarkup tgbotapi.InlineKeyboardMarkup) error {
+	return b.send(chatID, tgbotapi.NewEditMessageTextAndMarkup(chatID, messageID, text, replyMarkup))
+}
+
+func (b *BotHandler) AnswerCallback(callbackID string, text string) error {
+	return b.send(0, tgbotapi.NewCallback(callbackID, text))
 }
@@ -1,38 +1,107 @@
 package handlers
 
-import tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	"strconv"
+	"strings"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// callbackSeparator разделяет части callback-данных: <раздел>:<действие>:<аргументы...>
+const callbackSeparator = ":"
+
+// noopRoute маршрут кнопок, которые ничего не делают (например, номер страницы)
+const noopRoute = "noop"
+
+// CallbackFunc обрабатывает нажатие на inline-кнопку, args - части данных после маршрута.
+// Возвращаемый текст показывается всплывающим уведомлением, пустой - нажатие просто подтверждается
+type CallbackFunc func(callback *tgbotapi.CallbackQuery, args []string) string
+
+// CallbackHandler направляет нажатия на inline-кнопки обработчикам по маршруту из callback-данных
+// и всегда отвечает Telegram, чтобы у пользователя не зависал индикатор загрузки на кнопке
 type CallbackHandler struct {
 	botHandler BotHandler
+	routes     map[string]CallbackFunc
 }
 
 func NewCallbackHandler(botHandler *BotHandler) CallbackHandler {
-	return CallbackHandler{
+	h := CallbackHandler{
 		botHandler: *botHandler,
+		routes:     make(map[string]CallbackFunc),
 	}
+	h.Handle(noopRoute, func(*tgbotapi.CallbackQuery, []string) string { return "" })
+	return h
+}
+
+// Handle регистрирует обработчик маршрута, например "grp:select".
+// Данные "grp:select:123" попадут в него с аргументами ["123"]
+func (h *CallbackHandler) Handle(route string, handler CallbackFunc) {
+	h.routes[route] = handler
 }
-func (h *CallbackHandler) CallbackHandler(update *tgbotapi.Update) bool {
-	if update.CallbackQuery != nil {
+
+// RegisterRoutes направляет в CallbackHandler все нажатия на inline-кнопки
+func (h *CallbackHandler) RegisterRoutes(bot *BotHandler) {
+	bot.Handle(PriorityHigh, MatchCallbackPrefix(""), func(update *tgbotapi.Update) {
 		h.handleCallback(update.CallbackQuery)
-		return true
-	}
-	return false
+	})
 }
 
 func (h *CallbackHandler) handleCallback(callback *tgbotapi.CallbackQuery) {
-	switch callback.Data {
-	case "wireguard":
-		h.handleWireGuard(callback)
-	case "vless":
-		h.botHandler.SendTextMessage(callback.Message.Chat.ID, "Vless - это современный протокол, который обеспечивает высокую скорость и безопасность. Он использует криптографические методы для защиты данных и является простым в настройке. Vless подходит для большинства устройств и операционных систем.")
-	case "difference":
-		h.botHandler.SendTextMessage(callback.Message.Chat.ID, "WireGuard и Vless - это два разных VPN-протокола. WireGuard - это современный протокол, который обеспечивает высокую скорость и безопасность, используя криптографические методы. Vless - это более новый протокол, который также обеспечивает высокую скорость и безопасность, но имеет некоторые отличия в архитектуре и")
-	default:
-		h.botHandler.SendTextMessage(callback.Message.Chat.ID, "Неизвестная команда")
+	handler, args, ok := h.match(callback.Data)
+	// У кнопок из inline-режима нет сообщения, а обработчикам нужен чат
+	if !ok || callback.Message == nil {
+		h.botHandler.AnswerCallback(callback.ID, "Кнопка устарела, откройте меню еще раз")
+		return
+	}
+	h.botHandler.AnswerCallback(callback.ID, handler(callback, args))
+}
+
+// match ищет самый длинный зарегистрированный маршрут, с которого начинаются данные
+func (h *CallbackHandler) match(data string) (CallbackFunc, []string, bool) {
+	parts := strings.Split(data, callbackSeparator)
+	for i := len(parts); i > 0; i-- {
+		if handler, ok := h.routes[strings.Join(parts[:i], callbackSeparator)]; ok {
+			return handler, parts[i:], true
+		}
 	}
+	return nil, nil, false
 }
-func (h *CallbackHandler) handleWireGuard(callback *tgbotapi.CallbackQuery) {
-	msg := `WireGuard - это современный VPN-протокол, который обеспечивает высокую скорость и безопасность. Он использует криптографические методы для защиты данных и является простым в настройке. WireGuard подходит для большинства устройств и операционных систем.`
 
-	h.botHandler.SendTextMessage(callback.Message.Chat.ID, msg)
+// CallbackData собирает callback-данные кнопки из маршрута и аргументов.
+// Telegram ограничивает их 64 байтами, поэтому в аргументах передаются только идентификаторы
+func CallbackData(route string, args ...string) string {
+	return strings.Join(append([]string{route}, args...), callbackSeparator)
+}
+
+// pageCount возвращает количество страниц по perPage элементов (не меньше одной)
+func pageCount(total int, perPage int) int {
+	if total <= perPage {
+		return 1
+	}
+	return (total + perPage - 1) / perPage
+}
+
+// clampPage приводит номер страницы к допустимому, отрицательный означает последнюю страницу
+func clampPage(page int, pages int) int {
+	if page < 0 || page >= pages {
+		return pages - 1
+	}
+	return page
+}
+
+// pagerRow строит строку навигации по страницам. Номер страницы добавляется последним аргументом маршрута
+func pagerRow(route string, page int, pages int, args ...string) []tgbotapi.InlineKeyboardButton {
+	pageData := func(page int) string {
+		return CallbackData(route, append(append([]string(nil), args...), strconv.Itoa(page))...)
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("◀️", pageData(page-1)))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData(strconv.Itoa(page+1)+"/"+strconv.Itoa(pages), noopRoute))
+	if page+1 < pages {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("▶️", pageData(page+1)))
+	}
+	return row
 }
@@ -14,25 +14,14 @@ func NewCommandHandler(botHandler *BotHandler) CommandHandler {
 	}
 }
 
-func (h *CommandHandler) CommandHandler(update *tgbotapi.Update) bool {
-	if update.Message != nil && update.Message.IsCommand() {
-		h.handleCommand(update.Message)
-		return true
-	}
-	return false
-}
-
-func (h *CommandHandler) handleCommand(message *tgbotapi.Message) {
-	switch message.Command() {
-	case "start":
-		h.handleStartCommand(message)
-	}
-
+// RegisterRoutes регистрирует общие команды бота
+func (h *CommandHandler) RegisterRoutes(bot *BotHandler) {
+	bot.Handle(PriorityHigh, MatchCommand("start"), OnMessage(h.handleStartCommand))
 }
 
 func (h *CommandHandler) handleStartCommand(message *tgbotapi.Message) {
-	msg := "Привет! Нажми кнопку ниже и получи информацию о своем месте в конкурсном списке."
-	buttons := []string{"Получить"}
+	msg := "Привет! Укажи свой уникальный код поступающего, а затем нажми \"Получить\", чтобы узнать свое место в конкурсном списке."
+	buttons := []string{"Указать код", "Получить"}
 	commands := h.botHandler.SetKeyboardButtons(buttons, 2)
 
 	h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
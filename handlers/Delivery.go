@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxSendAttempts сколько раз пытаться отправить запрос при временных ошибках
+	maxSendAttempts = 3
+	// sendBackoff пауза перед повтором после временной ошибки, удваивается с каждой попыткой
+	sendBackoff = time.Second
+)
+
+// ErrChatBlocked возвращается, если пользователь заблокировал бота или чат недоступен по другой причине
+var ErrChatBlocked = errors.New("чат недоступен")
+
+// delivery общие для всех копий BotHandler обработчики недоставленных сообщений
+type delivery struct {
+	mutex        sync.RWMutex
+	blockedHooks []func(chatID int64)
+}
+
+// OnChatBlocked регистрирует обработчик, который вызывается, когда Telegram отвечает,
+// что чат недоступен (пользователь заблокировал бота, удалил аккаунт и т.п.)
+func (b *BotHandler) OnChatBlocked(hook func(chatID int64)) {
+	b.delivery.mutex.Lock()
+	defer b.delivery.mutex.Unlock()
+	b.delivery.blockedHooks = append(b.delivery.blockedHooks, hook)
+}
+
+// deliver делает одну попытку отправить запрос в Telegram. Если запрос стоит повторить, возвращает паузу
+// перед повтором: при 429 - указанное в retry_after время, при сетевых ошибках и ошибках сервера -
+// нарастающую паузу. При 403 сообщает обработчикам OnChatBlocked.
+// chatID нужен только для обработчиков, для запросов без чата (ответ на callback) передается 0
+func (b *BotHandler) deliver(chatID int64, request tgbotapi.Chattable, attempt int) (time.Duration, error) {
+	_, err := b.bot.Request(request)
+	if err == nil {
+		return 0, nil
+	}
+
+	// Без ответа Telegram (сетевая ошибка) запрос мог не дойти, поэтому повторяем его
+	wait := sendBackoff << (attempt - 1)
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch code := apiErrorCode(apiErr); {
+		case code == http.StatusForbidden:
+			b.chatBlocked(chatID)
+			log.Printf("Чат %d недоступен: %v", chatID, err)
+			return 0, fmt.Errorf("%w: %v", ErrChatBlocked, err)
+		case code == http.StatusTooManyRequests:
+			if apiErr.RetryAfter > 0 {
+				wait = time.Duration(apiErr.RetryAfter) * time.Second
+			}
+		case code < http.StatusInternalServerError:
+			log.Printf("Ошибка отправки в чат %d: %v", chatID, err)
+			return 0, err
+		}
+	}
+
+	if attempt >= maxSendAttempts {
+		log.Printf("Не удалось отправить в чат %d после %d попыток: %v", chatID, maxSendAttempts, err)
+		return 0, err
+	}
+	return wait, err
+}
+
+func (b *BotHandler) chatBlocked(chatID int64) {
+	if chatID == 0 {
+		return
+	}
+	b.delivery.mutex.RLock()
+	hooks := b.delivery.blockedHooks
+	b.delivery.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(chatID)
+	}
+}
+
+// apiErrorCode возвращает код ошибки Telegram. При загрузке файлов библиотека не заполняет Code,
+// поэтому для фотографий код восстанавливается по тексту ошибки
+func apiErrorCode(err *tgbotapi.Error) int {
+	switch {
+	case err.Code != 0:
+		return err.Code
+	case strings.HasPrefix(err.Message, "Forbidden"):
+		return http.StatusForbidden
+	case strings.HasPrefix(err.Message, "Too Many Requests"), err.RetryAfter > 0:
+		return http.StatusTooManyRequests
+	}
+	return http.StatusBadRequest
+}
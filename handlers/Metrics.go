@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Metrics считает обработанные обновления по типам и время их обработки.
+// Отдает значения в текстовом формате Prometheus
+type Metrics struct {
+	mutex     sync.Mutex
+	counts    map[string]int64         // тип обновления -> количество
+	durations map[string]time.Duration // тип обновления -> суммарное время обработки
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counts:    make(map[string]int64),
+		durations: make(map[string]time.Duration),
+	}
+}
+
+// Middleware учитывает каждое обновление, в том числе завершившееся паникой
+func (m *Metrics) Middleware() Middleware {
+	return func(next UpdateFunc) UpdateFunc {
+		return func(update *tgbotapi.Update) {
+			kind := updateKind(update)
+			started := time.Now()
+
+			defer func() {
+				m.mutex.Lock()
+				m.counts[kind]++
+				m.durations[kind] += time.Since(started)
+				m.mutex.Unlock()
+			}()
+			next(update)
+		}
+	}
+}
+
+// ServeHTTP отдает метрики для сбора Prometheus
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	kinds := make([]string, 0, len(m.counts))
+	for kind := range m.counts {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintln(w, "# TYPE bot_updates_total counter")
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "bot_updates_total{kind=%q} %d\n", kind, m.counts[kind])
+	}
+	fmt.Fprintln(w, "# TYPE bot_update_duration_seconds_total counter")
+	for _, kind := range kinds {
+		fmt.Fprintf(w, "bot_update_duration_seconds_total{kind=%q} %.3f\n", kind, m.durations[kind].Seconds())
+	}
+}
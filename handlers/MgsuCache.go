@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bot/catalog"
+	"bot/fetcher"
+	"bot/models"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// listCache хранит последнюю разобранную версию каждого конкурсного списка по URL вместе с данными
+// для условных запросов и не дает загружать одну страницу параллельно. Новая версия определяется
+// по ответу сервера и хэшу тела страницы, а не по времени формирования: список может измениться и без него
+type listCache struct {
+	mutex  sync.Mutex
+	latest map[string]cachedList // URL -> последняя разобранная версия
+	calls  map[string]*listCall  // URL -> загрузка, которая идет прямо сейчас
+}
+
+type cachedList struct {
+	snapshot   models.Snapshot
+	bodyHash   string             // Хэш тела страницы, чтобы не разбирать неизменившуюся страницу
+	validators fetcher.Validators // ETag и Last-Modified для условного запроса
+	checkedAt  time.Time          // Когда страница последний раз загружалась
+}
+
+type listCall struct {
+	done     chan struct{}
+	snapshot models.Snapshot
+	err      error
+}
+
+func newListCache() *listCache {
+	return &listCache{
+		latest: make(map[string]cachedList),
+		calls:  make(map[string]*listCall),
+	}
+}
+
+// fetchList возвращает разобранный список группы. Если страница загружалась не раньше maxAge назад,
+// используется кэш; одновременные запросы одного URL ждут одну общую загрузку.
+// maxAge = 0 всегда загружает страницу заново (так делает мониторинг)
+func (h *MgsuHandler) fetchList(group catalog.Group, maxAge time.Duration) (models.Snapshot, error) {
+	cache := h.cache
+
+	cache.mutex.Lock()
+	if cached, ok := cache.latest[group.URL]; ok && maxAge > 0 && time.Since(cached.checkedAt) < maxAge {
+		cache.mutex.Unlock()
+		return cached.snapshot, nil
+	}
+	if call, ok := cache.calls[group.URL]; ok {
+		cache.mutex.Unlock()
+		<-call.done
+		return call.snapshot, call.err
+	}
+	call := &listCall{done: make(chan struct{})}
+	cache.calls[group.URL] = call
+	cache.mutex.Unlock()
+
+	call.snapshot, call.err = h.downloadList(group)
+
+	cache.mutex.Lock()
+	delete(cache.calls, group.URL)
+	cache.mutex.Unlock()
+	close(call.done)
+
+	return call.snapshot, call.err
+}
+
+// cachedSnapshot возвращает последнюю разобранную версию списка группы без загрузки страницы
+func (h *MgsuHandler) cachedSnapshot(group catalog.Group) (models.Snapshot, bool) {
+	h.cache.mutex.Lock()
+	defer h.cache.mutex.Unlock()
+	cached, ok := h.cache.latest[group.URL]
+	return cached.snapshot, ok
+}
+
+// downloadList загружает страницу условным запросом и разбирает таблицу, только если страница
+// действительно изменилась: сервер не ответил 304 и хэш тела отличается от закэшированного
+func (h *MgsuHandler) downloadList(group catalog.Group) (models.Snapshot, error) {
+	h.cache.mutex.Lock()
+	cached, hasCached := h.cache.latest[group.URL]
+	h.cache.mutex.Unlock()
+
+	var validators fetcher.Validators
+	if hasCached {
+		validators = cached.validators
+	}
+
+	source, err := h.sources.For(group)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	resp, err := source.Fetch(context.Background(), group, validators)
+	if err != nil {
+		return models.Snapshot{}, fmt.Errorf("ошибка загрузки страницы: %v", err)
+	}
+
+	if hasCached && (resp.NotModified || resp.Hash == cached.bodyHash) {
+		h.cache.mutex.Lock()
+		cached.checkedAt = time.Now()
+		h.cache.latest[group.URL] = cached
+		h.cache.mutex.Unlock()
+		return cached.snapshot, nil
+	}
+	if resp.NotModified {
+		// Кэш успели очистить, а сервер ответил 304 - загружаем страницу целиком
+		if resp, err = source.Fetch(context.Background(), group, fetcher.Validators{}); err != nil {
+			return models.Snapshot{}, fmt.Errorf("ошибка загрузки страницы: %v", err)
+		}
+	}
+
+	snapshot, err := h.parseList(group, source, resp.Body)
+	if err != nil {
+		return models.Snapshot{}, err
+	}
+
+	h.cache.mutex.Lock()
+	h.cache.latest[group.URL] = cachedList{
+		snapshot:   snapshot,
+		bodyHash:   resp.Hash,
+		validators: resp.Validators(),
+		checkedAt:  time.Now(),
+	}
+	h.cache.mutex.Unlock()
+
+	return snapshot, nil
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bot/catalog"
+	"fmt"
+	"strconv"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Маршруты callback-данных кнопок выбора конкурсных групп
+const (
+	groupSelectRoute = "grp:select" // grp:select:<groupID>:<страница>
+	groupPageRoute   = "grp:page"   // grp:page:<страница>
+	// legacyGroupRoute формат кнопок, отправленных до появления маршрутов: group:<groupID>
+	legacyGroupRoute = "group"
+)
+
+// groupsPerPage сколько групп показывается на одной странице списка
+const groupsPerPage = 8
+
+// MaxFollowedGroups максимальное количество отслеживаемых направлений (поступающий подает не более чем на пять)
+const MaxFollowedGroups = 5
+
+// sendGroupSelection отправляет пользователю список конкурсных групп для выбора
+func (h *MgsuHandler) sendGroupSelection(chatID int64) {
+	msg := fmt.Sprintf(
+		"🎓 Выберите направления, которые хотите отслеживать (не более %d).\n"+
+			"Нажмите на направление еще раз, чтобы перестать его отслеживать.",
+		MaxFollowedGroups,
+	)
+	h.botHandler.SendTextMessageWithMarkup(chatID, msg, h.groupSelectionMarkup(chatID, 0))
+}
+
+// groupSelectionMarkup строит страницу списка групп, отмечая выбранные пользователем
+func (h *MgsuHandler) groupSelectionMarkup(chatID int64, page int) tgbotapi.InlineKeyboardMarkup {
+	h.mutex.RLock()
+	followed := h.userGroups[chatID]
+	h.mutex.RUnlock()
+
+	groups := h.catalog.All()
+	pages := pageCount(len(groups), groupsPerPage)
+	page = clampPage(page, pages)
+	from := page * groupsPerPage
+	to := min(from+groupsPerPage, len(groups))
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, group := range groups[from:to] {
+		title := group.Title()
+		if containsGroup(followed, group.ID) {
+			title = "✅ " + title
+		}
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(title, CallbackData(groupSelectRoute, group.ID, strconv.Itoa(page))),
+		))
+	}
+	if pages > 1 {
+		keyboard = append(keyboard, pagerRow(groupPageRoute, page, pages))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+}
+
+// handleGroupPage перелистывает список групп
+func (h *MgsuHandler) handleGroupPage(callback *tgbotapi.CallbackQuery, args []string) string {
+	chatID := callback.Message.Chat.ID
+	page, err := strconv.Atoi(firstArg(args))
+	if err != nil {
+		return "Неизвестная страница"
+	}
+	h.botHandler.EditMessageReplyMarkup(chatID, callback.Message.MessageID, h.groupSelectionMarkup(chatID, page))
+	return ""
+}
+
+// handleGroupSelect добавляет нажатую группу в отслеживаемые или убирает ее оттуда
+func (h *MgsuHandler) handleGroupSelect(callback *tgbotapi.CallbackQuery, args []string) string {
+	chatID := callback.Message.Chat.ID
+
+	group, ok := h.catalog.Get(firstArg(args))
+	if !ok {
+		return "Направление не найдено"
+	}
+	page := 0
+	if len(args) > 1 {
+		page, _ = strconv.Atoi(args[1])
+	}
+
+	followed, err := h.ToggleUserGroup(chatID, group.ID)
+	if err != nil {
+		fmt.Printf("Ошибка сохранения направлений пользователя %d: %v\n", chatID, err)
+		return err.Error()
+	}
+	h.botHandler.EditMessageReplyMarkup(chatID, callback.Message.MessageID, h.groupSelectionMarkup(chatID, page))
+
+	// После выбора первого направления предлагаем указать код
+	if _, hasCode := h.GetUserCode(chatID); followed && !hasCode {
+		h.requestUniqueCode(chatID)
+	}
+
+	if followed {
+		return "Добавлено: " + group.Title()
+	}
+	return "Удалено: " + group.Title()
+}
+
+// ToggleUserGroup добавляет группу в отслеживаемые или убирает ее оттуда.
+// Возвращает true, если группа теперь отслеживается
+func (h *MgsuHandler) ToggleUserGroup(chatID int64, groupID string) (bool, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	current := h.userGroups[chatID]
+	var updated []string
+	followed := !containsGroup(current, groupID)
+	if followed {
+		if len(current) >= MaxFollowedGroups {
+			return false, fmt.Errorf("можно отслеживать не более %d направлений", MaxFollowedGroups)
+		}
+		updated = append(append(updated, current...), groupID)
+	} else {
+		for _, id := range current {
+			if id != groupID {
+				updated = append(updated, id)
+			}
+		}
+	}
+
+	if err := h.store.SaveUserGroups(chatID, updated); err != nil {
+		return false, err
+	}
+	h.userGroups[chatID] = updated
+	return followed, nil
+}
+
+// followedGroups возвращает отслеживаемые пользователем группы, которые есть в каталоге
+func (h *MgsuHandler) followedGroups(chatID int64) []catalog.Group {
+	h.mutex.RLock()
+	groupIDs := h.userGroups[chatID]
+	h.mutex.RUnlock()
+
+	var groups []catalog.Group
+	for _, id := range groupIDs {
+		if group, ok := h.catalog.Get(id); ok {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// monitoredGroups возвращает группы, которые отслеживает хотя бы один подписчик
+func (h *MgsuHandler) monitoredGroups() []catalog.Group {
+	h.mutex.RLock()
+	seen := make(map[string]bool)
+	for chatID := range h.subscribedUsers {
+		for _, id := range h.userGroups[chatID] {
+			seen[id] = true
+		}
+	}
+	h.mutex.RUnlock()
+
+	var groups []catalog.Group
+	for _, group := range h.catalog.All() {
+		if seen[group.ID] {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+func containsGroup(groupIDs []string, groupID string) bool {
+	for _, id := range groupIDs {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// firstArg возвращает первый аргумент callback-данных или пустую строку
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
@@ -1,130 +1,379 @@
 package handlers
 
 import (
+	"bot/admission"
+	"bot/analytics"
+	"bot/catalog"
+	"bot/config"
+	"bot/models"
+	"bot/sources"
+	"bot/storage"
+	"errors"
 	"fmt"
-	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/PuerkitoBio/goquery"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
-// StudentInfo представляет информацию о позиции студента в конкурсном списке
-type StudentInfo struct {
-	BudgetPlaces    int    // Количество бюджетных мест
-	Position        string // Позиция в формате "69/107"
-	MinPassingScore int    // Минимальный проходной балл
-	CreationDate    string // Дата создания списка
-	CreationTime    string // Время создания списка
-	Direction       string // Направление обучения
-}
-
-// StudentEntry представляет запись о студенте в таблице
-type StudentEntry struct {
-	Number                string
-	UniqueCode            string
-	TotalScore            string
-	SubjectScore          string
-	Math                  string
-	IT                    string
-	Russian               string
-	GeneralAchievements   string
-	AdmissionConsent      string
-	Priority              string
-	MainHighPriority      string
-	IsMainHighPriority    string
-	HighPassingPriority   string
-	IsHighPassingPriority string
-	PPR9                  string
-	PPR10                 string
-	BVIBasis              string
-}
-
 type MgsuHandler struct {
-	botHandler           BotHandler
-	lastCreationDateTime string
-	subscribedUsers      map[int64]int // chatID -> uniqueCode
-	mutex                sync.RWMutex
-	monitoringActive     bool
+	botHandler            BotHandler
+	store                 storage.Storage
+	catalog               *catalog.Catalog
+	lastCreationDateTimes map[string]string             // groupID -> "дата время" последнего просмотренного списка
+	lastListHashes        map[string]string             // groupID -> хэш содержимого последнего просмотренного списка
+	subscribedUsers       map[int64]int                 // chatID -> uniqueCode
+	userCodes             map[int64]int                 // chatID -> uniqueCode, указанный пользователем
+	userGroups            map[int64][]string            // chatID -> отслеживаемые конкурсные группы
+	mutedGroups           map[int64][]string            // chatID -> группы, уведомления по которым отключены
+	positionModes         map[int64]models.PositionMode // chatID -> режим подсчета позиции для уведомлений
+	awaitingCode          map[int64]bool                // chatID -> ожидаем ввод уникального кода
+	knownHeaders          map[string][]string           // groupID -> заголовки таблицы последней корректной версии
+	pendingHeaders        map[string][]string           // groupID -> изменившиеся заголовки, которые еще не принял администратор
+	layoutAlerts          map[string]string             // groupID -> последнее отправленное администраторам предупреждение
+	predicting            map[int64]bool                // chatID -> прогноз зачисления строится прямо сейчас
+	rankedHistories       map[string]rankedHistory      // groupID -> ранжированная история последней версии списка
+	cache                 *listCache
+	sources               *sources.Registry // Источники конкурсных списков разных вузов
+	mutex                 sync.RWMutex
+	monitoringActive      bool
 }
 
-func NewMgsuHandler(botHandler *BotHandler) MgsuHandler {
+// NewMgsuHandler создает обработчик и восстанавливает подписки, коды пользователей,
+// выбранные конкурсные группы и время последних просмотренных списков из хранилища
+func NewMgsuHandler(botHandler *BotHandler, store storage.Storage, groups *catalog.Catalog, listSources *sources.Registry) (MgsuHandler, error) {
+	subscribedUsers, err := store.LoadSubscriptions()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки подписок: %v", err)
+	}
+
+	userCodes, err := store.LoadUserCodes()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки кодов пользователей: %v", err)
+	}
+
+	userGroups, err := store.LoadUserGroups()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки выбранных направлений: %v", err)
+	}
+
+	mutedGroups, err := store.LoadMutedGroups()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки настроек уведомлений: %v", err)
+	}
+
+	positionModes, err := store.LoadPositionModes()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки режимов уведомлений: %v", err)
+	}
+
+	lastCreationDateTimes, err := store.LoadLastCreationDateTimes()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки времени формирования списков: %v", err)
+	}
+
+	lastListHashes, err := store.LoadListHashes()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки хэшей списков: %v", err)
+	}
+
+	knownHeaders, err := store.LoadLayouts()
+	if err != nil {
+		return MgsuHandler{}, fmt.Errorf("ошибка загрузки структуры списков: %v", err)
+	}
+
 	return MgsuHandler{
-		botHandler:       *botHandler,
-		subscribedUsers:  make(map[int64]int),
-		mutex:            sync.RWMutex{},
-		monitoringActive: false,
+		botHandler:            *botHandler,
+		store:                 store,
+		catalog:               groups,
+		lastCreationDateTimes: lastCreationDateTimes,
+		lastListHashes:        lastListHashes,
+		subscribedUsers:       subscribedUsers,
+		userCodes:             userCodes,
+		userGroups:            userGroups,
+		mutedGroups:           mutedGroups,
+		positionModes:         positionModes,
+		awaitingCode:          make(map[int64]bool),
+		predicting:            make(map[int64]bool),
+		rankedHistories:       make(map[string]rankedHistory),
+		knownHeaders:          knownHeaders,
+		pendingHeaders:        make(map[string][]string),
+		layoutAlerts:          make(map[string]string),
+		cache:                 newListCache(),
+		sources:               listSources,
+		mutex:                 sync.RWMutex{},
+		monitoringActive:      false,
+	}, nil
+}
+
+// codePattern сообщение, похожее на уникальный код поступающего
+var codePattern = regexp.MustCompile(`^\s*\d+\s*$`)
+
+// RegisterRoutes регистрирует команды, кнопки клавиатуры и ввод кода МГСУ
+func (h *MgsuHandler) RegisterRoutes(bot *BotHandler) {
+	bot.Handle(PriorityHigh, MatchCommand("setcode"), h.menuAction(h.handleSetCodeCommand))
+	bot.Handle(PriorityHigh, MatchCommand("groups"), h.menuAction(h.handleGroupsCommand))
+	bot.Handle(PriorityHigh, MatchCommand("history"), h.menuAction(h.handleHistoryCommand))
+	bot.Handle(PriorityHigh, MatchCommand("chart"), h.menuAction(h.handleChartCommand))
+	bot.Handle(PriorityHigh, MatchCommand("predict"), h.menuAction(h.handlePredictCommand))
+	bot.Handle(PriorityHigh, MatchCommand("mode"), h.menuAction(h.handleModeCommand))
+
+	bot.Handle(PriorityNormal, MatchText("Получить"), h.menuAction(h.handleGetCommand))
+	bot.Handle(PriorityNormal, MatchText("Подписаться"), h.menuAction(h.handleSubscribeCommand))
+	bot.Handle(PriorityNormal, MatchText("Отписаться"), h.menuAction(h.handleUnsubscribeCommand))
+	bot.Handle(PriorityNormal, MatchText("Указать код"), h.menuAction(h.handleRequestCodeCommand))
+	bot.Handle(PriorityNormal, MatchText("Направления"), h.menuAction(h.handleGroupsCommand))
+	bot.Handle(PriorityNormal, MatchText("История"), h.menuAction(h.handleHistoryCommand))
+	bot.Handle(PriorityNormal, MatchText("График"), h.menuAction(h.handleChartCommand))
+	bot.Handle(PriorityNormal, MatchText("Прогноз"), h.menuAction(h.handlePredictCommand))
+	bot.Handle(PriorityNormal, MatchText("Режим уведомлений"), h.menuAction(h.handleModeCommand))
+
+	// Код принимаем и без нажатия "Указать код". Остальной текст уходит в fallback,
+	// который заодно прекращает ожидание кода
+	bot.Handle(PriorityLow, MatchRegexp(codePattern), OnMessage(h.handleCodeMessage))
+
+	bot.SetFallback(OnMessage(h.handleUnknownMessage))
+}
+
+// RegisterCallbacks регистрирует обработчики inline-кнопок МГСУ
+func (h *MgsuHandler) RegisterCallbacks(callbacks *CallbackHandler) {
+	callbacks.Handle(groupSelectRoute, h.handleGroupSelect)
+	callbacks.Handle(legacyGroupRoute, h.handleGroupSelect)
+	callbacks.Handle(groupPageRoute, h.handleGroupPage)
+	callbacks.Handle(subscriptionToggleRoute, h.handleSubscriptionToggle)
+	callbacks.Handle(modeSetRoute, h.handleModeSet)
+	callbacks.Handle(legacyModeRoute, h.handleModeSet)
+	callbacks.Handle(historyPageRoute, h.handleHistoryPage)
+	callbacks.Handle(layoutAckRoute, h.handleLayoutAck)
+}
+
+// menuAction оборачивает обработчик команды или кнопки меню: выбор другого действия
+// прекращает ожидание уникального кода
+func (h *MgsuHandler) menuAction(handler func(*tgbotapi.Message)) UpdateFunc {
+	return OnMessage(func(message *tgbotapi.Message) {
+		h.cancelCodeInput(message.Chat.ID)
+		handler(message)
+	})
+}
+
+func (h *MgsuHandler) handleGroupsCommand(message *tgbotapi.Message) {
+	h.sendGroupSelection(message.Chat.ID)
+}
+
+func (h *MgsuHandler) handleModeCommand(message *tgbotapi.Message) {
+	h.sendModeSelection(message.Chat.ID)
+}
+
+func (h *MgsuHandler) handleRequestCodeCommand(message *tgbotapi.Message) {
+	h.requestUniqueCode(message.Chat.ID)
+}
+
+func (h *MgsuHandler) handleCodeMessage(message *tgbotapi.Message) {
+	h.handleCodeInput(message.Chat.ID, message.Text)
+}
+
+// handleUnknownMessage отвечает на сообщения, которые бот не понял, и показывает меню
+func (h *MgsuHandler) handleUnknownMessage(message *tgbotapi.Message) {
+	msg := "🤔 Не понимаю это сообщение. Воспользуйтесь кнопками меню ниже."
+	if h.isAwaitingCode(message.Chat.ID) {
+		h.cancelCodeInput(message.Chat.ID)
+		msg = "❌ Это не похоже на уникальный код: он состоит только из цифр.\n" +
+			"Отправьте код числом или выберите действие в меню ниже."
+	}
+	commands := h.botHandler.SetKeyboardButtons(h.mainButtons(message.Chat.ID), 2)
+	h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
+}
+
+// handleSetCodeCommand обрабатывает команду /setcode [код]
+func (h *MgsuHandler) handleSetCodeCommand(message *tgbotapi.Message) {
+	if args := strings.TrimSpace(message.CommandArguments()); args != "" {
+		h.handleCodeInput(message.Chat.ID, args)
+		return
+	}
+	h.requestUniqueCode(message.Chat.ID)
+}
+
+// requestUniqueCode просит пользователя прислать свой уникальный код.
+// Код проверяется по спискам выбранных направлений, поэтому сначала нужно их выбрать
+func (h *MgsuHandler) requestUniqueCode(chatID int64) {
+	if len(h.followedGroups(chatID)) == 0 {
+		h.sendGroupSelection(chatID)
+		return
+	}
+
+	h.mutex.Lock()
+	h.awaitingCode[chatID] = true
+	h.mutex.Unlock()
+
+	h.botHandler.SendTextMessage(chatID, "✏️ Отправьте ваш уникальный код поступающего (число из столбца \"Уникальный код\" конкурсного списка).\n"+
+		"Чтобы отменить, выберите любое действие в меню.")
+}
+
+// handleCodeInput проверяет присланный код по конкурсному списку и сохраняет его
+func (h *MgsuHandler) handleCodeInput(chatID int64, text string) {
+	uniqueCode, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || uniqueCode <= 0 {
+		h.botHandler.SendTextMessage(chatID, "❌ Код должен быть положительным числом. Попробуйте еще раз.")
+		return
+	}
+
+	if len(h.followedGroups(chatID)) == 0 {
+		h.sendGroupSelection(chatID)
+		return
+	}
+
+	if err := h.validateUniqueCode(chatID, uniqueCode); err != nil {
+		h.botHandler.SendTextMessage(chatID, fmt.Sprintf("❌ %v\n\nПроверьте код и отправьте его еще раз.", err))
+		return
+	}
+
+	if err := h.SetUserCode(chatID, uniqueCode); err != nil {
+		fmt.Printf("Ошибка сохранения кода пользователя %d: %v\n", chatID, err)
+		h.botHandler.SendTextMessage(chatID, "❌ Не удалось сохранить код, попробуйте позже.")
+		return
 	}
+
+	msg := fmt.Sprintf("✅ Код %d сохранен. Теперь можно получить информацию о своем месте в списке.", uniqueCode)
+	commands := h.botHandler.SetKeyboardButtons(h.mainButtons(chatID), 2)
+	h.botHandler.SendTextMessageWithKeyboardMarkup(chatID, msg, commands)
 }
 
-func (h *MgsuHandler) MgsuHandler(update *tgbotapi.Update) bool {
-	if update.Message != nil {
-		h.handleCommand(update.Message)
-		return true
+// validateUniqueCode проверяет, что код присутствует в столбце "Уникальный код"
+// хотя бы одного конкурсного списка из выбранных пользователем направлений
+func (h *MgsuHandler) validateUniqueCode(chatID int64, uniqueCode int) error {
+	codeStr := strconv.Itoa(uniqueCode)
+
+	var lastErr error
+	for _, group := range h.followedGroups(chatID) {
+		snapshot, err := h.fetchList(group, config.LIST_CACHE_TTL)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		for _, student := range snapshot.Entries {
+			if student.UniqueCode == codeStr {
+				return nil
+			}
+		}
+	}
+
+	if lastErr != nil {
+		return lastErr
 	}
-	return false
+	return fmt.Errorf("код %d не найден в конкурсных списках выбранных направлений", uniqueCode)
 }
 
-func (h *MgsuHandler) handleCommand(message *tgbotapi.Message) {
-	switch message.Text {
-	case "Получить":
-		h.handleGetCommand(message)
-	case "Подписаться":
-		h.handleSubscribeCommand(message)
-	case "Отписаться":
-		h.handleUnsubscribeCommand(message)
+// mainButtons возвращает кнопки основного меню с учетом подписки пользователя
+func (h *MgsuHandler) mainButtons(chatID int64) []string {
+	if h.IsSubscribed(chatID) {
+		return []string{"Получить", "Отписаться", "История", "График", "Прогноз", "Направления", "Режим уведомлений", "Указать код"}
 	}
+	return []string{"Получить", "Подписаться", "История", "График", "Прогноз", "Направления", "Режим уведомлений", "Указать код"}
 }
 
 func (h *MgsuHandler) handleGetCommand(message *tgbotapi.Message) {
-	// Пример использования парсера
-	// В реальной реализации uniqueCode должен приходить от пользователя
-	uniqueCode := 3838475 // Пример кода из таблицы
+	uniqueCode, ok := h.GetUserCode(message.Chat.ID)
+	if !ok {
+		h.requestUniqueCode(message.Chat.ID)
+		return
+	}
 
-	studentInfo, err := h.ParseStudentPosition(uniqueCode)
-	if err != nil {
-		msg := fmt.Sprintf("Ошибка при получении информации: %v", err)
-		h.botHandler.SendTextMessage(message.Chat.ID, msg)
+	groups := h.followedGroups(message.Chat.ID)
+	if len(groups) == 0 {
+		h.sendGroupSelection(message.Chat.ID)
 		return
 	}
 
-	msg := fmt.Sprintf(
+	var blocks []string
+	for _, group := range groups {
+		studentInfo, err := h.ParseStudentPosition(group, uniqueCode)
+		if err != nil {
+			blocks = append(blocks, fmt.Sprintf("🎓 %s\nОшибка при получении информации: %v", group.Title(), err))
+			continue
+		}
+		blocks = append(blocks, h.formatStudentInfo(uniqueCode, studentInfo))
+		h.saveStudentInfo(message.Chat.ID, group.ID, studentInfo)
+	}
+	msg := strings.Join(blocks, "\n\n")
+
+	// Проверяем, подписан ли пользователь, и показываем соответствующие кнопки
+	commands := h.botHandler.SetKeyboardButtons(h.mainButtons(message.Chat.ID), 2)
+	h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
+}
+
+// formatStudentInfo форматирует информацию о позиции студента в одном конкурсном списке
+func (h *MgsuHandler) formatStudentInfo(uniqueCode int, studentInfo *models.StudentInfo) string {
+	warning := ""
+	if studentInfo.Stale {
+		warning = "⚠️ Страница списка сейчас не читается, показана последняя сохраненная версия.\n"
+	}
+
+	return warning + fmt.Sprintf(
 		"Информация о студенте с кодом %d:\n"+
-			"🎯 Позиция: %s\n"+
-			"📚 Количество бюджетных мест: %d\n"+
-			"📊 Минимальный проходной балл: %d\n"+
+			"🎯 Позиция: %s среди всех, %s среди подавших согласие\n"+
+			"%s"+
+			"%s"+
+			"📚 Количество бюджетных мест: %s\n"+
+			"📊 Минимальный проходной балл: %s\n"+
+			"%s"+
 			"📅 Дата создания: %s\n"+
 			"⏰ Время создания: %s\n"+
 			"🎓 Направление: %s",
 		uniqueCode,
 		studentInfo.Position,
-		studentInfo.BudgetPlaces,
-		studentInfo.MinPassingScore,
+		studentInfo.ConsentPosition,
+		h.formatPriorityStatus(studentInfo),
+		h.formatCategories(studentInfo),
+		h.formatPlaces(studentInfo),
+		h.formatPassingScore(studentInfo),
+		h.formatChance(studentInfo),
 		h.formatDate(studentInfo.CreationDate),
 		studentInfo.CreationTime,
 		studentInfo.Direction,
 	)
+}
 
-	// Проверяем, подписан ли пользователь, и показываем соответствующие кнопки
-	var buttons []string
-	if h.IsSubscribed(message.Chat.ID) {
-		buttons = []string{"Получить", "Отписаться"}
-	} else {
-		buttons = []string{"Получить", "Подписаться"}
+// formatChance форматирует оценку шанса на бюджетное место
+func (h *MgsuHandler) formatChance(studentInfo *models.StudentInfo) string {
+	if !studentInfo.ChanceKnown {
+		return ""
 	}
+	return fmt.Sprintf("🎲 Шанс на бюджетное место: %.0f%% (оценка)\n", studentInfo.Chance*100)
+}
 
-	commands := h.botHandler.SetKeyboardButtons(buttons, 2)
-	h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
+// formatCategories сообщает, сколько поступающих вне общего конкурса стоят выше пользователя
+func (h *MgsuHandler) formatCategories(studentInfo *models.StudentInfo) string {
+	switch studentInfo.Category {
+	case models.CategoryBVI:
+		return "🏅 Вы поступаете без вступительных испытаний\n"
+	case models.CategoryPriority:
+		return "🏅 У вас преимущественное право (ч. 10 ст. 71), вы занимаете место до общего конкурса\n"
+	}
+	if studentInfo.BVIAbove == 0 && studentInfo.PPRAbove == 0 {
+		return ""
+	}
+	return fmt.Sprintf("🏅 Выше вас БВИ: %d, с преимущественным правом: %d\n", studentInfo.BVIAbove, studentInfo.PPRAbove)
+}
+
+// formatPlaces форматирует количество мест, выделяя места общего конкурса
+func (h *MgsuHandler) formatPlaces(studentInfo *models.StudentInfo) string {
+	if studentInfo.GeneralPlaces == studentInfo.BudgetPlaces {
+		return strconv.Itoa(studentInfo.BudgetPlaces)
+	}
+	return fmt.Sprintf("%d (по общему конкурсу: %d)", studentInfo.BudgetPlaces, studentInfo.GeneralPlaces)
 }
 
 // handleSubscribeCommand обрабатывает команду подписки на уведомления
 func (h *MgsuHandler) handleSubscribeCommand(message *tgbotapi.Message) {
-	// В реальной реализации uniqueCode должен запрашиваться у пользователя
-	// Пока используем тестовый код
-	uniqueCode := 3838475
+	uniqueCode, ok := h.GetUserCode(message.Chat.ID)
+	if !ok {
+		h.requestUniqueCode(message.Chat.ID)
+		return
+	}
 
 	// Проверяем, не подписан ли пользователь уже
 	if h.IsSubscribed(message.Chat.ID) {
@@ -133,13 +382,17 @@ func (h *MgsuHandler) handleSubscribeCommand(message *tgbotapi.Message) {
 				"Вы получаете уведомления при обновлении списков каждые 5 минут.",
 			uniqueCode,
 		)
-		buttons := []string{"Получить", "Отписаться"}
-		commands := h.botHandler.SetKeyboardButtons(buttons, 2)
+		commands := h.botHandler.SetKeyboardButtons(h.mainButtons(message.Chat.ID), 2)
 		h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
+		h.sendSubscriptionSettings(message.Chat.ID)
 		return
 	}
 
-	h.AddSubscription(message.Chat.ID, uniqueCode)
+	if err := h.AddSubscription(message.Chat.ID, uniqueCode); err != nil {
+		fmt.Printf("Ошибка сохранения подписки %d: %v\n", message.Chat.ID, err)
+		h.botHandler.SendTextMessage(message.Chat.ID, "❌ Не удалось оформить подписку, попробуйте позже.")
+		return
+	}
 
 	// Запускаем мониторинг, если он еще не запущен
 	h.StartMonitoring()
@@ -150,9 +403,9 @@ func (h *MgsuHandler) handleSubscribeCommand(message *tgbotapi.Message) {
 		uniqueCode,
 	)
 
-	buttons := []string{"Получить", "Отписаться"}
-	commands := h.botHandler.SetKeyboardButtons(buttons, 2)
+	commands := h.botHandler.SetKeyboardButtons(h.mainButtons(message.Chat.ID), 2)
 	h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
+	h.sendSubscriptionSettings(message.Chat.ID)
 }
 
 // handleUnsubscribeCommand обрабатывает команду отписки от уведомлений
@@ -160,163 +413,218 @@ func (h *MgsuHandler) handleUnsubscribeCommand(message *tgbotapi.Message) {
 	// Проверяем, подписан ли пользователь
 	if !h.IsSubscribed(message.Chat.ID) {
 		msg := "ℹ️ Вы не подписаны на уведомления об обновлениях списков."
-		buttons := []string{"Получить", "Подписаться"}
-		commands := h.botHandler.SetKeyboardButtons(buttons, 2)
+		commands := h.botHandler.SetKeyboardButtons(h.mainButtons(message.Chat.ID), 2)
 		h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
 		return
 	}
 
-	h.RemoveSubscription(message.Chat.ID)
+	if err := h.RemoveSubscription(message.Chat.ID); err != nil {
+		fmt.Printf("Ошибка удаления подписки %d: %v\n", message.Chat.ID, err)
+		h.botHandler.SendTextMessage(message.Chat.ID, "❌ Не удалось отменить подписку, попробуйте позже.")
+		return
+	}
 
 	msg := "❌ Вы отписались от уведомлений об обновлениях списков."
 
-	buttons := []string{"Получить", "Подписаться"}
-	commands := h.botHandler.SetKeyboardButtons(buttons, 2)
+	commands := h.botHandler.SetKeyboardButtons(h.mainButtons(message.Chat.ID), 2)
 	h.botHandler.SendTextMessageWithKeyboardMarkup(message.Chat.ID, msg, commands)
 }
 
-// ParseStudentPosition парсит страницу МГСУ и возвращает позицию студента
-func (h *MgsuHandler) ParseStudentPosition(uniqueCode int) (*StudentInfo, error) {
-	url := "https://mgsu.ru/2025/ks/bs/list.php?p=000000012_09.03.02_Informatsionnye_sistemy_i_tekhnologii_Ochnaya_Byudzhet_Obshchiy%20konkurs.html"
-
-	// Загружаем страницу
-	resp, err := http.Get(url)
+// ParseStudentPosition парсит конкурсный список группы и возвращает позицию студента
+func (h *MgsuHandler) ParseStudentPosition(group catalog.Group, uniqueCode int) (*models.StudentInfo, error) {
+	// Берем список из общего кэша: страница загружается и разбирается не чаще раза в LIST_CACHE_TTL
+	snapshot, err := h.fetchList(group, config.LIST_CACHE_TTL)
+	stale := false
 	if err != nil {
-		return nil, fmt.Errorf("ошибка загрузки страницы: %v", err)
+		// Если МГСУ изменил верстку, показываем последнюю корректную версию вместо неверных чисел
+		var layoutErr *sources.LayoutError
+		if !errors.As(err, &layoutErr) {
+			return nil, err
+		}
+		last, ok := h.lastGoodSnapshot(group.ID)
+		if !ok {
+			return nil, err
+		}
+		snapshot, stale = last, true
 	}
-	defer resp.Body.Close()
 
-	// Парсим HTML
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	studentInfo, err := h.calculateStudentInfo(group, snapshot, uniqueCode)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка парсинга HTML: %v", err)
+		return nil, err
 	}
+	studentInfo.Stale = stale
+	h.estimateChance(h.loadRankedHistory(group, snapshot), uniqueCode, studentInfo)
+	return studentInfo, nil
+}
 
-	// Ищем количество бюджетных мест
-	budgetPlaces := h.extractBudgetPlaces(doc)
+// rankedHistory ранжированные версии списка группы за analytics.Window, заканчивая версией version
+type rankedHistory struct {
+	version string
+	history []analytics.RankedSnapshot
+}
 
-	// Извлекаем дату и время создания списка
-	creationDate, creationTime := h.extractCreationDateTime(doc)
+// historyVersion отличает версии списка: содержимое может измениться без нового времени формирования
+func historyVersion(snapshot models.Snapshot) string {
+	return snapshot.Key() + "/" + snapshot.ContentHash()
+}
 
-	// Извлекаем направление обучения
-	direction := h.extractDirection(doc)
+// loadRankedHistory возвращает ранжированные версии списка группы за последние analytics.Window,
+// заканчивая snapshot. История загружается и ранжируется заново, только если snapshot - не та версия,
+// для которой она уже построена
+func (h *MgsuHandler) loadRankedHistory(group catalog.Group, snapshot models.Snapshot) []analytics.RankedSnapshot {
+	version := historyVersion(snapshot)
+	h.mutex.RLock()
+	cached, ok := h.rankedHistories[group.ID]
+	h.mutex.RUnlock()
+	if ok && cached.version == version {
+		return cached.history
+	}
 
-	// Парсим таблицу и извлекаем данные студентов
-	students, err := h.parseStudentTable(doc)
+	history, err := h.store.LoadSnapshotsSince(group.ID, snapshot.CreatedAt().Add(-analytics.Window))
 	if err != nil {
-		return nil, fmt.Errorf("ошибка парсинга таблицы: %v", err)
+		fmt.Printf("Ошибка загрузки истории списка %s: %v\n", group.ID, err)
 	}
-
-	// Фильтруем студентов по высшему проходному приоритету (галочка в 6-м столбце "Это высший проходной приоритет")
-	filteredStudents := h.filterByHighPassingPriority(students)
-
-	// Ищем позицию студента с указанным кодом
-	position, found := h.findStudentPosition(filteredStudents, uniqueCode)
-	if !found {
-		return nil, fmt.Errorf("студент с кодом %d не найден или не имеет высший проходной приоритет", uniqueCode)
+	// Текущая версия могла еще не попасть в хранилище
+	if len(history) == 0 || history[len(history)-1].Key() != snapshot.Key() {
+		history = append(history, snapshot)
+	} else {
+		history[len(history)-1] = snapshot
 	}
+	ranked := analytics.RankHistory(history)
 
-	// Вычисляем минимальный проходной балл
-	minPassingScore := h.calculateMinPassingScore(filteredStudents, budgetPlaces)
-
-	return &StudentInfo{
-		BudgetPlaces:    budgetPlaces,
-		Position:        fmt.Sprintf("%d/%d", position, budgetPlaces),
-		MinPassingScore: minPassingScore,
-		CreationDate:    creationDate,
-		CreationTime:    creationTime,
-		Direction:       direction,
-	}, nil
+	h.mutex.Lock()
+	h.rankedHistories[group.ID] = rankedHistory{version: version, history: ranked}
+	h.mutex.Unlock()
+	return ranked
 }
 
-// extractBudgetPlaces извлекает количество бюджетных мест из HTML
-func (h *MgsuHandler) extractBudgetPlaces(doc *goquery.Document) int {
-	// Ищем ячейку с текстом "Всего мест: X."
-	budgetText := ""
-	doc.Find("td").Each(func(i int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if strings.Contains(text, "Всего мест:") {
-			budgetText = text
-			return
-		}
-	})
+// appendRankedHistory добавляет новую версию списка к уже построенной истории группы,
+// не загружая остальные версии из хранилища
+func (h *MgsuHandler) appendRankedHistory(group catalog.Group, snapshot models.Snapshot) {
+	h.mutex.RLock()
+	cached, ok := h.rankedHistories[group.ID]
+	h.mutex.RUnlock()
+	if !ok {
+		return // История построится при первом запросе
+	}
 
-	// Извлекаем число из текста формата "Всего мест: 107."
-	if budgetText != "" {
-		// Ищем паттерн "Всего мест: число."
-		if strings.HasPrefix(budgetText, "Всего мест:") {
-			// Убираем "Всего мест: " и "."
-			numberPart := strings.TrimPrefix(budgetText, "Всего мест:")
-			numberPart = strings.TrimSpace(numberPart)
-			numberPart = strings.TrimSuffix(numberPart, ".")
-
-			if num, err := strconv.Atoi(numberPart); err == nil {
-				if num > 0 && num < 1000 { // разумные границы для количества мест
-					return num
-				}
-			}
+	created := snapshot.CreatedAt()
+	since := created.Add(-analytics.Window)
+	var history []analytics.RankedSnapshot
+	for _, ranked := range cached.history {
+		// Версия с тем же временем формирования заменяется новой
+		if !ranked.CreatedAt.Before(since) && ranked.CreatedAt.Before(created) {
+			history = append(history, ranked)
 		}
 	}
+	history = append(history, analytics.RankHistory([]models.Snapshot{snapshot})...)
 
-	// Если не нашли, возвращаем значение по умолчанию
-	return 107
+	h.mutex.Lock()
+	h.rankedHistories[group.ID] = rankedHistory{version: historyVersion(snapshot), history: history}
+	h.mutex.Unlock()
 }
 
-// extractCreationDateTime извлекает дату и время создания списка из HTML
-func (h *MgsuHandler) extractCreationDateTime(doc *goquery.Document) (string, string) {
-	// Ищем ячейку с текстом "Дата формирования - X. Время формирования - Y."
-	var creationDate, creationTime string
+// estimateChance добавляет к информации о позиции оценку шанса на бюджетное место по ранжированной истории списка
+func (h *MgsuHandler) estimateChance(history []analytics.RankedSnapshot, uniqueCode int, studentInfo *models.StudentInfo) {
+	estimate, ok := analytics.Calculate(history, strconv.Itoa(uniqueCode))
+	studentInfo.Chance = estimate.Probability
+	studentInfo.ChanceKnown = ok
+}
 
-	doc.Find("td").Each(func(i int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if strings.Contains(text, "Дата формирования") && strings.Contains(text, "Время формирования") {
-			// Парсим строку формата "Дата формирования - 31.07.2025. Время формирования - 10:01:01."
+// calculateStudentInfo вычисляет позицию студента по уже разобранной версии списка
+func (h *MgsuHandler) calculateStudentInfo(group catalog.Group, snapshot models.Snapshot, uniqueCode int) (*models.StudentInfo, error) {
+	budgetPlaces := snapshot.BudgetPlaces
 
-			// Ищем дату после "Дата формирования - "
-			if dateStart := strings.Index(text, "Дата формирования - "); dateStart != -1 {
-				dateStart += len("Дата формирования - ")
-				// Ищем следующую точку после даты
-				if dateEnd := strings.Index(text[dateStart:], ". Время формирования"); dateEnd != -1 {
-					creationDate = strings.TrimSpace(text[dateStart : dateStart+dateEnd])
-				}
-			}
+	// Фильтруем студентов по высшему проходному приоритету (галочка в 6-м столбце "Это высший проходной приоритет")
+	// и ранжируем их: сумма баллов, сумма по предметам, баллы по предметам, индивидуальные достижения
+	highPassing := admission.Rank(admission.HighPassingPriority(snapshot.Entries))
 
-			// Ищем время после "Время формирования - "
-			if timeStart := strings.Index(text, "Время формирования - "); timeStart != -1 {
-				timeStart += len("Время формирования - ")
-				// Ищем следующую точку после времени
-				if timeEnd := strings.Index(text[timeStart:], "."); timeEnd != -1 {
-					creationTime = strings.TrimSpace(text[timeStart : timeStart+timeEnd])
-				} else {
-					// Если точки нет, берем до конца строки
-					creationTime = strings.TrimSpace(text[timeStart:])
-				}
-			}
-			return
+	// Ищем позицию студента с указанным кодом. Отметка высшего проходного приоритета у поступающего есть
+	// не больше чем в одном списке: в остальных позиция считается среди всех поступающих
+	filteredStudents := highPassing
+	rank, found := h.findStudentPosition(filteredStudents, uniqueCode)
+	if !found {
+		filteredStudents = admission.Rank(snapshot.Entries)
+		if rank, found = h.findStudentPosition(filteredStudents, uniqueCode); !found {
+			return nil, fmt.Errorf("студент с кодом %d не найден в списке", uniqueCode)
 		}
-	})
+	}
+	student := filteredStudents[rank-1]
+
+	// БВИ и поступающие по ч. 10 занимают места первыми, поэтому позиция и проходной балл
+	// считаются среди общего конкурса на оставшиеся места. Проходной балл определяют только
+	// поступающие с высшим проходным приоритетом
+	general, generalPlaces := admission.GeneralCompetition(highPassing, budgetPlaces)
+	var bviAbove, pprAbove int
+	for _, above := range filteredStudents[:rank-1] {
+		switch above.Category() {
+		case models.CategoryBVI:
+			bviAbove++
+		case models.CategoryPriority, models.CategoryPreferential:
+			pprAbove++
+		}
+	}
+	position, places := admission.Position(filteredStudents, rank, budgetPlaces)
+
+	// На последних этапах зачисления учитываются только подавшие согласие: считаем позицию и среди них
+	var consenting []models.StudentEntry
+	consentRank := 0
+	for i, entry := range filteredStudents {
+		if i == rank-1 {
+			consenting = append(consenting, entry)
+			consentRank = len(consenting)
+		} else if entry.HasConsent() {
+			consenting = append(consenting, entry)
+		}
+	}
+	consentPosition, consentPlaces := admission.Position(consenting, consentRank, budgetPlaces)
 
-	return creationDate, creationTime
-}
+	// Вычисляем минимальный проходной балл
+	cutOff := admission.CalculateCutOff(general, generalPlaces)
+
+	// Запоминаем, кто стоит выше и кто из них подал согласие, чтобы потом сравнивать версии списка.
+	// Список стоящих выше не nil даже пустым: так он отличается от версий, сохраненных до его появления
+	var consentAbove []string
+	above := make([]string, 0, rank-1)
+	for _, entry := range filteredStudents[:rank-1] {
+		above = append(above, entry.UniqueCode)
+		if entry.HasConsent() {
+			consentAbove = append(consentAbove, entry.UniqueCode)
+		}
+	}
 
-// extractDirection извлекает направление обучения из HTML
-func (h *MgsuHandler) extractDirection(doc *goquery.Document) string {
-	// Ищем ячейку с текстом "Конкурсная группа - X"
-	direction := ""
+	totalScore, _ := strconv.Atoi(student.TotalScore)
 
-	doc.Find("td").Each(func(i int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if strings.Contains(text, "Конкурсная группа") {
-			// Извлекаем направление из строки формата "Конкурсная группа - 09.03.02_Информационные_системы_и_технологии_Очная_Бюджет_Общий конкурс"
-			direction = strings.TrimPrefix(text, "Конкурсная группа - ")
-			direction = strings.TrimSpace(direction)
-			// Заменяем подчеркивания на пробелы для лучшей читаемости
-			direction = strings.ReplaceAll(direction, "_", " ")
-			return
-		}
-	})
+	notHighPassing := !strings.Contains(student.IsHighPassingPriority, "✓")
+	passingPriority := ""
+	if priority, err := strconv.Atoi(strings.TrimSpace(student.HighPassingPriority)); notHighPassing && err == nil && priority > 0 {
+		passingPriority = strconv.Itoa(priority)
+	}
 
-	return direction
+	return &models.StudentInfo{
+		BudgetPlaces:    budgetPlaces,
+		GeneralPlaces:   generalPlaces,
+		Position:        fmt.Sprintf("%d/%d", position, places),
+		PositionNumber:  position,
+		PositionPlaces:  places,
+		ConsentPosition: fmt.Sprintf("%d/%d", consentPosition, consentPlaces),
+		ConsentNumber:   consentPosition,
+		ConsentPlaces:   consentPlaces,
+		Category:        student.Category(),
+		BVIAbove:        bviAbove,
+		PPRAbove:        pprAbove,
+		TotalScore:      totalScore,
+		MinPassingScore: cutOff.Score,
+		CutOffStatus:    cutOff.Status,
+		TiedAtCutOff:    cutOff.Tied,
+		CreationDate:    snapshot.CreationDate,
+		CreationTime:    snapshot.CreationTime,
+		Direction:       group.Title(),
+		ConsentAbove:    consentAbove,
+		Above:           above,
+		NotHighPassing:  notHighPassing,
+		PassingPriority: passingPriority,
+	}, nil
 }
 
 // formatDate форматирует дату из формата DD.MM.YYYY в читаемый вид
@@ -325,73 +633,8 @@ func (h *MgsuHandler) formatDate(dateStr string) string {
 	return dateStr
 }
 
-// parseStudentTable парсит таблицу студентов
-func (h *MgsuHandler) parseStudentTable(doc *goquery.Document) ([]StudentEntry, error) {
-	var students []StudentEntry
-
-	// Ищем таблицу с данными
-	doc.Find("table").Each(func(i int, table *goquery.Selection) {
-		// Проверяем, что это нужная нам таблица по заголовкам
-		headers := table.Find("tr.header-row th")
-		if headers.Length() < 16 {
-			return
-		}
-
-		// Парсим строки данных
-		table.Find("tr.data-row").Each(func(j int, row *goquery.Selection) {
-			cells := row.Find("td")
-			if cells.Length() >= 16 {
-				student := StudentEntry{
-					Number:                cells.Eq(0).Text(),                     // №
-					UniqueCode:            strings.TrimSpace(cells.Eq(1).Text()),  // Уникальный код
-					Priority:              strings.TrimSpace(cells.Eq(2).Text()),  // Приоритет
-					AdmissionConsent:      strings.TrimSpace(cells.Eq(3).Text()),  // Согласие на зачисление
-					HighPassingPriority:   strings.TrimSpace(cells.Eq(4).Text()),  // Высший проходной приоритет
-					IsHighPassingPriority: strings.TrimSpace(cells.Eq(5).Text()),  // Это высший проходной приоритет
-					MainHighPriority:      strings.TrimSpace(cells.Eq(6).Text()),  // Основной высший приоритет
-					TotalScore:            strings.TrimSpace(cells.Eq(7).Text()),  // Сумма баллов
-					SubjectScore:          strings.TrimSpace(cells.Eq(8).Text()),  // Сумма по предметам
-					Math:                  strings.TrimSpace(cells.Eq(9).Text()),  // Матем / ЧиИГ
-					IT:                    strings.TrimSpace(cells.Eq(10).Text()), // ИиИКТ / Физика / БезопЖизнедеят
-					Russian:               strings.TrimSpace(cells.Eq(11).Text()), // РусЯз
-					GeneralAchievements:   strings.TrimSpace(cells.Eq(12).Text()), // Общие ИД
-					BVIBasis:              strings.TrimSpace(cells.Eq(13).Text()), // Основание БВИ
-					PPR9:                  strings.TrimSpace(cells.Eq(14).Text()), // ППР (ч.9 с. 71 273-ФЗ)
-					PPR10:                 strings.TrimSpace(cells.Eq(15).Text()), // ППР (ч.10 с. 71 273-ФЗ)
-					IsMainHighPriority:    strings.TrimSpace(cells.Eq(16).Text()), // Номер предложения (используем поле IsMainHighPriority)
-					// Остальные столбцы пока не используем:
-					// cells.Eq(17) - Размещено на РВР
-					// cells.Eq(18) - ID заказчика (нет на РВР)
-					// cells.Eq(19) - Целевые ИД
-				}
-				students = append(students, student)
-			}
-		})
-	})
-
-	if len(students) == 0 {
-		return nil, fmt.Errorf("таблица студентов не найдена")
-	}
-
-	return students, nil
-}
-
-// filterByHighPassingPriority фильтрует студентов по наличию галочки в колонке "Это высший проходной приоритет"
-func (h *MgsuHandler) filterByHighPassingPriority(students []StudentEntry) []StudentEntry {
-	var filtered []StudentEntry
-
-	for _, student := range students {
-		// Проверяем наличие галочки (✓) в колонке "Это высший проходной приоритет"
-		if strings.Contains(student.IsHighPassingPriority, "✓") {
-			filtered = append(filtered, student)
-		}
-	}
-
-	return filtered
-}
-
 // findStudentPosition находит позицию студента в отфильтрованном списке
-func (h *MgsuHandler) findStudentPosition(students []StudentEntry, uniqueCode int) (int, bool) {
+func (h *MgsuHandler) findStudentPosition(students []models.StudentEntry, uniqueCode int) (int, bool) {
 	codeStr := strconv.Itoa(uniqueCode)
 
 	for i, student := range students {
@@ -403,24 +646,29 @@ func (h *MgsuHandler) findStudentPosition(students []StudentEntry, uniqueCode in
 	return 0, false
 }
 
-// calculateMinPassingScore вычисляет минимальный проходной балл
-func (h *MgsuHandler) calculateMinPassingScore(students []StudentEntry, budgetPlaces int) int {
-	if len(students) < budgetPlaces {
-		// Если студентов меньше чем мест, берем балл последнего
-		if len(students) > 0 {
-			if score, err := strconv.Atoi(students[len(students)-1].TotalScore); err == nil {
-				return score
-			}
-		}
-		return 0
+// formatPriorityStatus поясняет позицию поступающего, у которого это направление не высший проходной приоритет
+func (h *MgsuHandler) formatPriorityStatus(studentInfo *models.StudentInfo) string {
+	switch {
+	case !studentInfo.NotHighPassing:
+		return ""
+	case studentInfo.PassingPriority != "":
+		return fmt.Sprintf("↗️ Вы проходите по более высокому приоритету (%s), на это направление место не займете. Позиция посчитана среди всех поступающих списка\n", studentInfo.PassingPriority)
 	}
+	return "ℹ️ Сейчас вы не проходите ни по одному приоритету. Позиция посчитана среди всех поступающих списка\n"
+}
 
-	// Берем балл студента на последнем проходном месте
-	if score, err := strconv.Atoi(students[budgetPlaces-1].TotalScore); err == nil {
-		return score
+// formatPassingScore форматирует проходной балл с учетом того, есть ли конкурс
+func (h *MgsuHandler) formatPassingScore(studentInfo *models.StudentInfo) string {
+	switch studentInfo.CutOffStatus {
+	case models.CutOffNoCompetition:
+		return "конкурса нет, поступающих меньше, чем мест"
+	case models.CutOffUnknown:
+		return "неизвестен"
 	}
-
-	return 0
+	if studentInfo.TiedAtCutOff > 1 {
+		return fmt.Sprintf("%d (на последнее место претендуют %d поступающих с равными баллами)", studentInfo.MinPassingScore, studentInfo.TiedAtCutOff)
+	}
+	return strconv.Itoa(studentInfo.MinPassingScore)
 }
 
 // StartMonitoring запускает мониторинг изменений в списках
@@ -443,18 +691,86 @@ func (h *MgsuHandler) StopMonitoring() {
 	h.mutex.Unlock()
 }
 
-// AddSubscription добавляет пользователя в список уведомлений
-func (h *MgsuHandler) AddSubscription(chatID int64, uniqueCode int) {
+// AddSubscription добавляет пользователя в список уведомлений и сохраняет подписку в хранилище
+func (h *MgsuHandler) AddSubscription(chatID int64, uniqueCode int) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
+	if err := h.store.SaveSubscription(chatID, uniqueCode); err != nil {
+		return err
+	}
 	h.subscribedUsers[chatID] = uniqueCode
+	return nil
 }
 
-// RemoveSubscription удаляет пользователя из списка уведомлений
-func (h *MgsuHandler) RemoveSubscription(chatID int64) {
+// RemoveSubscription удаляет пользователя из списка уведомлений и из хранилища
+func (h *MgsuHandler) RemoveSubscription(chatID int64) error {
 	h.mutex.Lock()
 	defer h.mutex.Unlock()
+	if err := h.store.DeleteSubscription(chatID); err != nil {
+		return err
+	}
 	delete(h.subscribedUsers, chatID)
+	return nil
+}
+
+// SetUserCode сохраняет уникальный код пользователя
+func (h *MgsuHandler) SetUserCode(chatID int64, uniqueCode int) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if err := h.store.SaveUserCode(chatID, uniqueCode); err != nil {
+		return err
+	}
+	// Показанная информация относится к прежнему коду: изменения по новому коду считаются с нуля
+	if previous, exists := h.userCodes[chatID]; exists && previous != uniqueCode {
+		if err := h.store.DeleteStudentInfo(chatID); err != nil {
+			return err
+		}
+	}
+	h.userCodes[chatID] = uniqueCode
+	delete(h.awaitingCode, chatID)
+
+	// Если пользователь подписан, уведомления должны приходить по новому коду
+	if _, exists := h.subscribedUsers[chatID]; exists {
+		if err := h.store.SaveSubscription(chatID, uniqueCode); err != nil {
+			return err
+		}
+		h.subscribedUsers[chatID] = uniqueCode
+	}
+	return nil
+}
+
+// GetUserCode возвращает уникальный код пользователя, если он был указан
+func (h *MgsuHandler) GetUserCode(chatID int64) (int, bool) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	uniqueCode, exists := h.userCodes[chatID]
+	return uniqueCode, exists
+}
+
+// isAwaitingCode проверяет, ждем ли мы от пользователя ввода уникального кода
+func (h *MgsuHandler) isAwaitingCode(chatID int64) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.awaitingCode[chatID]
+}
+
+// cancelCodeInput прекращает ожидание уникального кода от пользователя
+func (h *MgsuHandler) cancelCodeInput(chatID int64) {
+	h.mutex.Lock()
+	delete(h.awaitingCode, chatID)
+	h.mutex.Unlock()
+}
+
+// HandleChatBlocked отписывает пользователя, который заблокировал бота: уведомления ему все равно не дойдут
+func (h *MgsuHandler) HandleChatBlocked(chatID int64) {
+	if !h.IsSubscribed(chatID) {
+		return
+	}
+	if err := h.RemoveSubscription(chatID); err != nil {
+		fmt.Printf("Ошибка удаления подписки %d: %v\n", chatID, err)
+		return
+	}
+	fmt.Printf("Пользователь %d заблокировал бота, подписка отменена\n", chatID)
 }
 
 // IsSubscribed проверяет, подписан ли пользователь на уведомления
@@ -483,83 +799,215 @@ func (h *MgsuHandler) monitoringLoop() {
 	}
 }
 
-// checkForUpdates проверяет обновления списка
+// checkForUpdates проверяет обновления всех списков, на которые есть подписчики
 func (h *MgsuHandler) checkForUpdates() {
-	url := "https://mgsu.ru/2025/ks/bs/list.php?p=000000012_09.03.02_Informatsionnye_sistemy_i_tekhnologii_Ochnaya_Byudzhet_Obshchiy%20konkurs.html"
-
-	resp, err := http.Get(url)
-	if err != nil {
-		fmt.Printf("Ошибка при проверке обновлений: %v\n", err)
-		return
+	for _, group := range h.monitoredGroups() {
+		h.checkGroupForUpdates(group)
 	}
-	defer resp.Body.Close()
+}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+// checkGroupForUpdates проверяет обновление списка одной конкурсной группы
+func (h *MgsuHandler) checkGroupForUpdates(group catalog.Group) {
+	// Мониторинг всегда загружает страницу заново и обновляет общий кэш.
+	// При изменении верстки не рассылаем уведомления: parseList уже сообщил администраторам
+	snapshot, err := h.fetchList(group, 0)
 	if err != nil {
-		fmt.Printf("Ошибка парсинга при проверке обновлений: %v\n", err)
+		fmt.Printf("Ошибка при проверке обновлений %s: %v\n", group.ID, err)
 		return
 	}
-
-	// Извлекаем дату и время создания
-	creationDate, creationTime := h.extractCreationDateTime(doc)
-	currentDateTime := fmt.Sprintf("%s %s", creationDate, creationTime)
+	currentDateTime := fmt.Sprintf("%s %s", snapshot.CreationDate, snapshot.CreationTime)
+	currentHash := snapshot.ContentHash()
 
 	h.mutex.Lock()
-	lastDateTime := h.lastCreationDateTime
+	lastDateTime := h.lastCreationDateTimes[group.ID]
+	lastHash := h.lastListHashes[group.ID]
 	h.mutex.Unlock()
 
-	// Если время изменилось, отправляем уведомления
-	if lastDateTime != "" && lastDateTime != currentDateTime {
-		fmt.Printf("Обнаружено обновление списка: %s -> %s\n", lastDateTime, currentDateTime)
-		h.sendUpdateNotifications()
+	// Список мог измениться и без обновления времени формирования, поэтому сравниваем еще и содержимое.
+	// Пустой lastHash - хэш еще не сохранялся, такую версию считаем по одному времени
+	changed := lastDateTime != currentDateTime || (lastHash != "" && lastHash != currentHash)
+
+	// Каждую новую версию списка сохраняем целиком, чтобы потом строить историю
+	if changed {
+		h.saveSnapshot(snapshot)
+		h.appendRankedHistory(group, snapshot)
+	}
+
+	// Если список изменился, отправляем уведомления
+	if lastDateTime != "" && changed {
+		if lastDateTime == currentDateTime {
+			fmt.Printf("Список %s изменился без обновления времени формирования (%s)\n", group.ID, currentDateTime)
+		} else {
+			fmt.Printf("Обнаружено обновление списка %s: %s -> %s\n", group.ID, lastDateTime, currentDateTime)
+		}
+		h.sendUpdateNotifications(group, snapshot)
 	}
 
-	// Обновляем последнее время
+	// Обновляем последнее время и хэш
 	h.mutex.Lock()
-	h.lastCreationDateTime = currentDateTime
+	h.lastCreationDateTimes[group.ID] = currentDateTime
+	h.lastListHashes[group.ID] = currentHash
 	h.mutex.Unlock()
+
+	if lastDateTime != currentDateTime {
+		if err := h.store.SaveLastCreationDateTime(group.ID, currentDateTime); err != nil {
+			fmt.Printf("Ошибка сохранения времени формирования списка %s: %v\n", group.ID, err)
+		}
+	}
+	if lastHash != currentHash {
+		if err := h.store.SaveListHash(group.ID, currentHash); err != nil {
+			fmt.Printf("Ошибка сохранения хэша списка %s: %v\n", group.ID, err)
+		}
+	}
+}
+
+// saveSnapshot сохраняет версию конкурсного списка и удаляет устаревшие версии
+func (h *MgsuHandler) saveSnapshot(snapshot models.Snapshot) {
+	if err := h.store.SaveSnapshot(snapshot); err != nil {
+		fmt.Printf("Ошибка сохранения версии списка %s: %v\n", snapshot.GroupID, err)
+		return
+	}
+
+	removed, err := h.store.PruneSnapshots(snapshot.GroupID, config.SNAPSHOT_RETENTION, config.SNAPSHOT_MAX_PER_GROUP)
+	if err != nil {
+		fmt.Printf("Ошибка удаления устаревших версий списка %s: %v\n", snapshot.GroupID, err)
+	} else if removed > 0 {
+		fmt.Printf("Удалено устаревших версий списка %s: %d\n", snapshot.GroupID, removed)
+	}
 }
 
-// sendUpdateNotifications отправляет уведомления всем подписанным пользователям
-func (h *MgsuHandler) sendUpdateNotifications() {
+// notificationWorkers сколько уведомлений готовится одновременно. Темп отправки задает очередь
+// BotHandler, а ограниченное число отправителей не дает запускать по горутине на подписчика
+const notificationWorkers = 10
+
+// sendUpdateNotifications отправляет уведомления подписчикам, отслеживающим группу, и ждет,
+// пока все они уйдут. Позиции и шансы всех подписчиков считаются по одной уже загруженной версии списка
+// и один раз ранжированной истории
+func (h *MgsuHandler) sendUpdateNotifications(group catalog.Group, snapshot models.Snapshot) {
 	h.mutex.RLock()
 	subscribers := make(map[int64]int)
 	for chatID, uniqueCode := range h.subscribedUsers {
-		subscribers[chatID] = uniqueCode
+		if containsGroup(h.userGroups[chatID], group.ID) && !containsGroup(h.mutedGroups[chatID], group.ID) {
+			subscribers[chatID] = uniqueCode
+		}
 	}
 	h.mutex.RUnlock()
-
-	for chatID, uniqueCode := range subscribers {
-		go h.sendNotificationToUser(chatID, uniqueCode)
+	if len(subscribers) == 0 {
+		return
 	}
+	history := h.loadRankedHistory(group, snapshot)
+
+	chatIDs := make(chan int64)
+	var wg sync.WaitGroup
+	for i := 0; i < notificationWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chatID := range chatIDs {
+				h.sendNotificationToUser(chatID, subscribers[chatID], group, snapshot, history)
+			}
+		}()
+	}
+	for chatID := range subscribers {
+		chatIDs <- chatID
+	}
+	close(chatIDs)
+	wg.Wait()
 }
 
-// sendNotificationToUser отправляет уведомление конкретному пользователю
-func (h *MgsuHandler) sendNotificationToUser(chatID int64, uniqueCode int) {
-	studentInfo, err := h.ParseStudentPosition(uniqueCode)
+// sendNotificationToUser отправляет уведомление конкретному пользователю.
+// Если есть предыдущая версия информации, пользователь получает только то, что изменилось для него
+func (h *MgsuHandler) sendNotificationToUser(chatID int64, uniqueCode int, group catalog.Group, snapshot models.Snapshot, history []analytics.RankedSnapshot) {
+	studentInfo, err := h.calculateStudentInfo(group, snapshot, uniqueCode)
 	if err != nil {
-		errorMsg := fmt.Sprintf("❌ Ошибка при получении обновленной информации для кода %d: %v", uniqueCode, err)
-		h.botHandler.SendTextMessage(chatID, errorMsg)
+		errorMsg := fmt.Sprintf("❌ Ошибка при получении обновленной информации для кода %d (%s): %v", uniqueCode, group.Title(), err)
+		h.botHandler.Broadcast().SendTextMessage(chatID, errorMsg)
 		return
 	}
+	h.estimateChance(history, uniqueCode, studentInfo)
 
-	msg := fmt.Sprintf(
-		"🔔 ОБНОВЛЕНИЕ СПИСКА!\n\n"+
-			"Информация о студенте с кодом %d:\n"+
-			"🎯 Позиция: %s\n"+
-			"📚 Количество бюджетных мест: %d\n"+
-			"📊 Минимальный проходной балл: %d\n"+
-			"📅 Дата создания: %s\n"+
-			"⏰ Время создания: %s\n"+
-			"🎓 Направление: %s",
-		uniqueCode,
-		studentInfo.Position,
-		studentInfo.BudgetPlaces,
-		studentInfo.MinPassingScore,
+	previous, err := h.store.LoadStudentInfo(chatID, group.ID)
+	if err != nil {
+		fmt.Printf("Ошибка загрузки предыдущей информации %d/%s: %v\n", chatID, group.ID, err)
+	}
+
+	var msg string
+	if previous == nil {
+		msg = "🔔 ОБНОВЛЕНИЕ СПИСКА!\n\n" + h.formatStudentInfo(uniqueCode, studentInfo)
+	} else {
+		mode := h.positionMode(chatID)
+		msg = h.formatStudentDiff(models.Diff(*previous, *studentInfo, mode), studentInfo, mode)
+	}
+
+	// Если уведомление не дошло, не сохраняем информацию, чтобы изменения пришли со следующим обновлением
+	if err := h.botHandler.Broadcast().SendTextMessage(chatID, msg); err != nil {
+		return
+	}
+	h.saveStudentInfo(chatID, group.ID, studentInfo)
+}
+
+// formatStudentDiff форматирует изменения для пользователя с момента предыдущей версии списка
+func (h *MgsuHandler) formatStudentDiff(diff models.InfoDiff, studentInfo *models.StudentInfo, mode models.PositionMode) string {
+	header := fmt.Sprintf(
+		"🔔 ОБНОВЛЕНИЕ СПИСКА от %s %s\n🎓 %s\n\n",
 		h.formatDate(studentInfo.CreationDate),
 		studentInfo.CreationTime,
 		studentInfo.Direction,
 	)
 
-	h.botHandler.SendTextMessage(chatID, msg)
+	if !diff.HasChanges() {
+		msg := fmt.Sprintf("Для вас ничего не изменилось, позиция: %d/%d %s\n", diff.CurrentPosition, diff.CurrentPlaces, positionModeLabel(mode))
+		return header + strings.TrimSuffix(msg+h.formatPriorityStatus(studentInfo)+h.formatChance(studentInfo), "\n")
+	}
+
+	var lines []string
+	if diff.HighPassingChanged() {
+		if diff.CurrentHighPassing {
+			lines = append(lines, fmt.Sprintf("✅ Теперь это ваш высший проходной приоритет, позиция %s: %d/%d", positionModeLabel(mode), diff.CurrentPosition, diff.CurrentPlaces))
+		} else {
+			lines = append(lines, strings.TrimSuffix(h.formatPriorityStatus(studentInfo), "\n"))
+		}
+	}
+	if diff.PositionChanged() {
+		arrow := "⬆️"
+		if diff.CurrentPosition > diff.PreviousPosition {
+			arrow = "⬇️"
+		}
+		lines = append(lines, fmt.Sprintf("%s Позиция %s: %d → %d (из %d мест)", arrow, positionModeLabel(mode), diff.PreviousPosition, diff.CurrentPosition, diff.CurrentPlaces))
+	}
+	if diff.MinScoreChanged() {
+		if diff.PreviousCutOffStatus == models.CutOffKnown && diff.CurrentCutOffStatus == models.CutOffKnown {
+			lines = append(lines, fmt.Sprintf("📊 Минимальный проходной балл: %d → %d", diff.PreviousMinScore, diff.CurrentMinScore))
+		} else {
+			lines = append(lines, fmt.Sprintf("📊 Минимальный проходной балл: %s", h.formatPassingScore(studentInfo)))
+		}
+	}
+	if diff.BudgetPlacesChanged() {
+		lines = append(lines, fmt.Sprintf("📚 Бюджетных мест: %d → %d", diff.PreviousBudgetPlaces, diff.CurrentBudgetPlaces))
+	}
+	if diff.ConsentSubmitted > 0 {
+		lines = append(lines, fmt.Sprintf("✍️ Выше вас подали согласие: %d", diff.ConsentSubmitted))
+	}
+	if diff.ConsentWithdrawn > 0 {
+		lines = append(lines, fmt.Sprintf("↩️ Выше вас отозвали согласие: %d", diff.ConsentWithdrawn))
+	}
+	if diff.MovedAbove > 0 {
+		lines = append(lines, fmt.Sprintf("⤴️ Оказались выше вас: %d", diff.MovedAbove))
+	}
+	if diff.MovedBelow > 0 {
+		lines = append(lines, fmt.Sprintf("⤵️ Перестали быть выше вас: %d", diff.MovedBelow))
+	}
+
+	if chance := h.formatChance(studentInfo); chance != "" {
+		lines = append(lines, strings.TrimSuffix(chance, "\n"))
+	}
+
+	return header + strings.Join(lines, "\n")
+}
+
+// saveStudentInfo запоминает показанную пользователю информацию, чтобы следующее уведомление содержало только изменения
+func (h *MgsuHandler) saveStudentInfo(chatID int64, groupID string, studentInfo *models.StudentInfo) {
+	if err := h.store.SaveStudentInfo(chatID, groupID, *studentInfo); err != nil {
+		fmt.Printf("Ошибка сохранения информации %d/%s: %v\n", chatID, groupID, err)
+	}
 }
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bot/catalog"
+	"bot/charts"
+	"bot/models"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// historyPageRoute маршрут кнопок перелистывания истории: hist:page:<groupID>:<страница>
+const historyPageRoute = "hist:page"
+
+// historyDaysPerPage сколько дней истории показывается на одной странице
+const historyDaysPerPage = 10
+
+// handleHistoryCommand показывает, как менялись позиция и проходной балл по сохраненным версиям списков
+func (h *MgsuHandler) handleHistoryCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	uniqueCode, ok := h.GetUserCode(chatID)
+	if !ok {
+		h.requestUniqueCode(chatID)
+		return
+	}
+
+	groups := h.followedGroups(chatID)
+	if len(groups) == 0 {
+		h.sendGroupSelection(chatID)
+		return
+	}
+
+	// Каждое направление отдельным сообщением, сначала показываем последние дни
+	for _, group := range groups {
+		text, page, pages := h.formatHistory(group, uniqueCode, -1)
+		if pages > 1 {
+			h.botHandler.SendTextMessageWithMarkup(chatID, text, historyMarkup(group.ID, page, pages))
+		} else {
+			h.botHandler.SendTextMessage(chatID, text)
+		}
+	}
+}
+
+// handleHistoryPage перелистывает историю направления
+func (h *MgsuHandler) handleHistoryPage(callback *tgbotapi.CallbackQuery, args []string) string {
+	chatID := callback.Message.Chat.ID
+	if len(args) < 2 {
+		return "Неизвестная страница"
+	}
+	group, ok := h.catalog.Get(args[0])
+	if !ok {
+		return "Направление не найдено"
+	}
+	page, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "Неизвестная страница"
+	}
+	uniqueCode, ok := h.GetUserCode(chatID)
+	if !ok {
+		return "Сначала укажите уникальный код"
+	}
+
+	text, page, pages := h.formatHistory(group, uniqueCode, page)
+	h.botHandler.EditMessageTextWithMarkup(chatID, callback.Message.MessageID, text, historyMarkup(group.ID, page, pages))
+	return ""
+}
+
+// historyMarkup строит навигацию по страницам истории
+func historyMarkup(groupID string, page int, pages int) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(pagerRow(historyPageRoute, page, pages, groupID))
+}
+
+// formatHistory строит страницу истории по одной группе: одна строка на дату (берется последняя версия за день).
+// Отрицательный page означает последнюю страницу. Возвращает текст, номер показанной страницы и количество страниц
+func (h *MgsuHandler) formatHistory(group catalog.Group, uniqueCode int, page int) (string, int, int) {
+	header := fmt.Sprintf("📈 История для кода %d\n🎓 %s\n\n", uniqueCode, group.Title())
+
+	snapshots, err := h.store.LoadSnapshots(group.ID)
+	if err != nil {
+		return header + fmt.Sprintf("Ошибка загрузки истории: %v", err), 0, 1
+	}
+	if len(snapshots) == 0 {
+		return header + "История пока пуста: бот еще не сохранил ни одной версии этого списка.", 0, 1
+	}
+
+	var lines []string
+	for i, snapshot := range snapshots {
+		// Версии отсортированы по времени, поэтому пропускаем все, кроме последней за день
+		if i+1 < len(snapshots) && snapshots[i+1].CreationDate == snapshot.CreationDate {
+			continue
+		}
+
+		studentInfo, err := h.calculateStudentInfo(group, snapshot, uniqueCode)
+		if err != nil || studentInfo.NotHighPassing {
+			lines = append(lines, fmt.Sprintf("%s — нет среди поступающих с высшим приоритетом", h.formatDate(snapshot.CreationDate)))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%s — 🎯 %s, баллы: %d, проходной: %s",
+			h.formatDate(studentInfo.CreationDate),
+			studentInfo.Position,
+			studentInfo.TotalScore,
+			h.formatPassingScore(studentInfo),
+		))
+	}
+
+	pages := pageCount(len(lines), historyDaysPerPage)
+	page = clampPage(page, pages)
+	from := page * historyDaysPerPage
+	to := min(from+historyDaysPerPage, len(lines))
+	return header + strings.Join(lines[from:to], "\n"), page, pages
+}
+
+// handleChartCommand отправляет графики позиции и проходного балла по каждому направлению
+func (h *MgsuHandler) handleChartCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	uniqueCode, ok := h.GetUserCode(chatID)
+	if !ok {
+		h.requestUniqueCode(chatID)
+		return
+	}
+
+	groups := h.followedGroups(chatID)
+	if len(groups) == 0 {
+		h.sendGroupSelection(chatID)
+		return
+	}
+
+	for _, group := range groups {
+		err := h.sendChart(chatID, group, uniqueCode)
+		if errors.Is(err, ErrChatBlocked) {
+			return
+		}
+		if err != nil {
+			h.botHandler.SendTextMessage(chatID, fmt.Sprintf("📉 %s\n%v", group.Title(), err))
+		}
+	}
+}
+
+// sendChart рисует график по сохраненным версиям списка группы и отправляет его фотографией
+func (h *MgsuHandler) sendChart(chatID int64, group catalog.Group, uniqueCode int) error {
+	snapshots, err := h.store.LoadSnapshots(group.ID)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки истории: %v", err)
+	}
+
+	var positions, passingScores, totalScores []charts.Point
+	for _, snapshot := range snapshots {
+		studentInfo, err := h.calculateStudentInfo(group, snapshot, uniqueCode)
+		if err != nil {
+			continue
+		}
+		at := snapshot.CreatedAt()
+		// Позиции без высшего проходного приоритета считаются среди всех поступающих, их не с чем сравнивать
+		if !studentInfo.NotHighPassing {
+			positions = append(positions, charts.Point{Time: at, Value: float64(studentInfo.PositionNumber)})
+		}
+		// Проходной балл отмечаем, только когда он определен: без конкурса он равен минимуму испытаний
+		if studentInfo.CutOffStatus == models.CutOffKnown {
+			passingScores = append(passingScores, charts.Point{Time: at, Value: float64(studentInfo.MinPassingScore)})
+		}
+		totalScores = append(totalScores, charts.Point{Time: at, Value: float64(studentInfo.TotalScore)})
+	}
+	if len(positions) == 0 {
+		if len(totalScores) > 0 {
+			return fmt.Errorf("недостаточно данных для графика: это направление не было вашим высшим проходным приоритетом ни в одной версии списка")
+		}
+		return fmt.Errorf("недостаточно данных для графика: нет сохраненных версий списка с вашим кодом")
+	}
+
+	file, err := os.CreateTemp("", "chart-*.png")
+	if err != nil {
+		return fmt.Errorf("ошибка создания файла графика: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	err = charts.RenderPNG(file,
+		charts.Panel{
+			Title:   "Position",
+			Series:  []charts.Series{{Points: positions, Color: charts.Blue}},
+			InvertY: true,
+		},
+		charts.Panel{
+			Title: "Score",
+			Series: []charts.Series{
+				{Points: passingScores, Color: charts.Red},
+				{Points: totalScores, Color: charts.Green},
+			},
+		},
+	)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка построения графика: %v", err)
+	}
+
+	caption := fmt.Sprintf(
+		"📉 %s\n"+
+			"Сверху — ваша позиция (синяя линия), снизу — минимальный проходной балл (красная) и ваши баллы (зеленая).",
+		group.Title(),
+	)
+	return h.botHandler.SendTextMessageWithImage(chatID, caption, file.Name())
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"bot/catalog"
+	"bot/config"
+	"bot/models"
+	"bot/sources"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// parseList разбирает страницу источником группы и сообщает администраторам о расхождениях
+// структуры. Критичные расхождения возвращаются как *sources.LayoutError
+func (h *MgsuHandler) parseList(group catalog.Group, source sources.ListSource, body []byte) (models.Snapshot, error) {
+	list, err := source.Parse(group, body)
+	if err != nil {
+		var layoutErr *sources.LayoutError
+		if errors.As(err, &layoutErr) {
+			h.reportLayout(group, append(layoutErr.Problems, list.Warnings...))
+		}
+		return models.Snapshot{}, err
+	}
+
+	warnings := append(list.Warnings, h.checkHeaderDrift(group.ID, list.Headers)...)
+	h.reportLayout(group, warnings)
+
+	return list.Snapshot, nil
+}
+
+// layoutAckRoute маршрут кнопки, которой администратор принимает новую структуру таблицы: layout:ack:<groupID>
+const layoutAckRoute = "layout:ack"
+
+// checkHeaderDrift сравнивает заголовки таблицы с принятой структурой. Первая увиденная структура
+// принимается сразу, а изменения остаются предупреждениями, пока их не примет администратор
+func (h *MgsuHandler) checkHeaderDrift(groupID string, headers []string) []string {
+	h.mutex.Lock()
+	known, exists := h.knownHeaders[groupID]
+	changed := exists && strings.Join(known, "|") != strings.Join(headers, "|")
+	if !exists {
+		h.knownHeaders[groupID] = headers
+	}
+	if changed {
+		h.pendingHeaders[groupID] = headers
+	} else {
+		delete(h.pendingHeaders, groupID)
+	}
+	h.mutex.Unlock()
+
+	if !exists {
+		if err := h.store.SaveLayout(groupID, headers); err != nil {
+			fmt.Printf("Ошибка сохранения структуры списка %s: %v\n", groupID, err)
+		}
+		return nil
+	}
+	if !changed {
+		return nil
+	}
+
+	added, removed := diffHeaders(known, headers)
+	var warnings []string
+	if len(added) > 0 {
+		warnings = append(warnings, "появились столбцы: "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		warnings = append(warnings, "пропали столбцы: "+strings.Join(removed, ", "))
+	}
+	if len(added) == 0 && len(removed) == 0 {
+		warnings = append(warnings, "изменился порядок столбцов")
+	}
+	return warnings
+}
+
+// reportLayout сообщает администраторам о расхождениях структуры страницы.
+// Одинаковые предупреждения не повторяются, а после исправления приходит сообщение о восстановлении.
+// Если изменились заголовки, к предупреждению добавляется кнопка, которой их можно принять
+func (h *MgsuHandler) reportLayout(group catalog.Group, issues []string) {
+	alert := strings.Join(issues, "\n• ")
+
+	h.mutex.Lock()
+	previous := h.layoutAlerts[group.ID]
+	if alert == "" {
+		delete(h.layoutAlerts, group.ID)
+	} else {
+		h.layoutAlerts[group.ID] = alert
+	}
+	_, drifted := h.pendingHeaders[group.ID]
+	h.mutex.Unlock()
+
+	switch {
+	case alert == previous:
+		return
+	case alert == "":
+		h.sendAdminAlert(fmt.Sprintf("✅ Страница списка снова читается корректно\n🎓 %s", group.Title()))
+	default:
+		fmt.Printf("Изменилась структура списка %s: %s\n", group.ID, strings.Join(issues, "; "))
+		text := fmt.Sprintf("⚠️ Изменилась структура страницы списка\n🎓 %s\n%s\n\n• %s", group.Title(), group.URL, alert)
+		if !drifted {
+			h.sendAdminAlert(text)
+			return
+		}
+		markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Принять новую структуру", CallbackData(layoutAckRoute, group.ID)),
+		))
+		for _, chatID := range config.ADMIN_IDS {
+			h.botHandler.Broadcast().SendTextMessageWithMarkup(chatID, text, markup)
+		}
+	}
+}
+
+// handleLayoutAck принимает изменившиеся заголовки таблицы как новую известную структуру
+func (h *MgsuHandler) handleLayoutAck(callback *tgbotapi.CallbackQuery, args []string) string {
+	if !slices.Contains(config.ADMIN_IDS, callback.From.ID) {
+		return "Только для администраторов"
+	}
+	groupID := firstArg(args)
+
+	h.mutex.Lock()
+	headers, ok := h.pendingHeaders[groupID]
+	if ok {
+		h.knownHeaders[groupID] = headers
+		delete(h.pendingHeaders, groupID)
+		delete(h.layoutAlerts, groupID)
+	}
+	h.mutex.Unlock()
+
+	if !ok {
+		return "Структура уже принята или снова совпадает с прежней"
+	}
+	if err := h.store.SaveLayout(groupID, headers); err != nil {
+		fmt.Printf("Ошибка сохранения структуры списка %s: %v\n", groupID, err)
+		return "Не удалось сохранить структуру, попробуйте позже"
+	}
+	h.botHandler.EditMessageReplyMarkup(callback.Message.Chat.ID, callback.Message.MessageID, tgbotapi.NewInlineKeyboardMarkup())
+	return "Новая структура принята"
+}
+
+// sendAdminAlert отправляет сообщение всем администраторам бота
+func (h *MgsuHandler) sendAdminAlert(text string) {
+	for _, chatID := range config.ADMIN_IDS {
+		h.botHandler.Broadcast().SendTextMessage(chatID, text)
+	}
+}
+
+// lastGoodSnapshot возвращает последнюю сохраненную версию списка группы
+func (h *MgsuHandler) lastGoodSnapshot(groupID string) (models.Snapshot, bool) {
+	snapshot, err := h.store.LoadLatestSnapshot(groupID)
+	if err != nil {
+		fmt.Printf("Ошибка загрузки версии списка %s: %v\n", groupID, err)
+		return models.Snapshot{}, false
+	}
+	if snapshot == nil {
+		return models.Snapshot{}, false
+	}
+	return *snapshot, true
+}
+
+func diffHeaders(before, after []string) (added, removed []string) {
+	inBefore := make(map[string]bool, len(before))
+	for _, header := range before {
+		inBefore[header] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, header := range after {
+		inAfter[header] = true
+		if !inBefore[header] {
+			added = append(added, header)
+		}
+	}
+	for _, header := range before {
+		if !inAfter[header] {
+			removed = append(removed, header)
+		}
+	}
+	return added, removed
+}
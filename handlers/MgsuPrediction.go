@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"bot/admission"
+	"bot/catalog"
+	"bot/config"
+	"bot/models"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// predictionWorkers сколько списков загружать одновременно при моделировании зачисления.
+// Загружаются только списки, которых нет у мониторинга, поэтому хватает небольшого числа
+const predictionWorkers = 2
+
+// enrolmentPrediction результат моделирования зачисления по всем бюджетным направлениям
+type enrolmentPrediction struct {
+	outcome     admission.Outcome
+	groups      map[string]catalog.Group // Направления, участвовавшие в моделировании
+	failed      int                      // Сколько списков не удалось загрузить
+	consentOnly bool                     // Учитывались только поступающие с согласием
+}
+
+// handlePredictCommand показывает прогноз зачисления с учетом приоритетов поступающих
+func (h *MgsuHandler) handlePredictCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	uniqueCode, ok := h.GetUserCode(chatID)
+	if !ok {
+		h.requestUniqueCode(chatID)
+		return
+	}
+
+	followed := h.followedGroups(chatID)
+	if len(followed) == 0 {
+		h.sendGroupSelection(chatID)
+		return
+	}
+
+	if !h.startPrediction(chatID) {
+		h.botHandler.SendTextMessage(chatID, "⏳ Прогноз уже строится, результат придет отдельным сообщением")
+		return
+	}
+	h.botHandler.SendTextMessage(chatID, "⏳ Моделирую зачисление по всем бюджетным направлениям, результат придет отдельным сообщением...")
+
+	// Моделирование может занять время, поэтому не задерживает обработку других сообщений
+	go func() {
+		defer h.finishPrediction(chatID)
+
+		prediction, err := h.predictEnrolment(followed, uniqueCode)
+		var msg string
+		if err != nil {
+			msg = fmt.Sprintf("❌ Не удалось построить прогноз: %v", err)
+		} else {
+			msg = h.formatPrediction(uniqueCode, followed, prediction)
+		}
+
+		commands := h.botHandler.SetKeyboardButtons(h.mainButtons(chatID), 2)
+		h.botHandler.SendTextMessageWithKeyboardMarkup(chatID, msg, commands)
+	}()
+}
+
+// startPrediction отмечает, что для чата строится прогноз. Возвращает false, если он уже строится
+func (h *MgsuHandler) startPrediction(chatID int64) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.predicting[chatID] {
+		return false
+	}
+	h.predicting[chatID] = true
+	return true
+}
+
+func (h *MgsuHandler) finishPrediction(chatID int64) {
+	h.mutex.Lock()
+	delete(h.predicting, chatID)
+	h.mutex.Unlock()
+}
+
+// predictEnrolment моделирует распределение поступающих по приоритетам на бюджетных направлениях вузов,
+// которые выбрал пользователь. Списки берутся у мониторинга, загружаются только остальные
+func (h *MgsuHandler) predictEnrolment(followed []catalog.Group, uniqueCode int) (enrolmentPrediction, error) {
+	universities := make(map[string]bool)
+	followedIDs := make(map[string]bool)
+	for _, group := range followed {
+		universities[group.Source] = true
+		followedIDs[group.ID] = true
+	}
+	monitoredIDs := make(map[string]bool)
+	for _, group := range h.monitoredGroups() {
+		monitoredIDs[group.ID] = true
+	}
+
+	// Приоритеты действуют внутри одного вуза и только для бюджетных мест
+	var pool []catalog.Group
+	for _, group := range h.catalog.All() {
+		if universities[group.Source] && group.Funding == "бюджет" {
+			pool = append(pool, group)
+		}
+	}
+	if len(pool) == 0 {
+		return enrolmentPrediction{}, fmt.Errorf("среди выбранных направлений нет бюджетных")
+	}
+
+	snapshots := make([]models.Snapshot, len(pool))
+	loaded := make([]bool, len(pool))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < predictionWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range jobs {
+				group := pool[index]
+				snapshot, ok := h.predictionSnapshot(group, monitoredIDs[group.ID], followedIDs[group.ID])
+				if !ok {
+					continue
+				}
+				snapshots[index] = snapshot
+				loaded[index] = true
+			}
+		}()
+	}
+	for i := range pool {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	prediction := enrolmentPrediction{groups: make(map[string]catalog.Group)}
+	var programs []admission.Program
+	for i, group := range pool {
+		if !loaded[i] {
+			prediction.failed++
+			continue
+		}
+		prediction.groups[group.ID] = group
+		programs = append(programs, admission.Program{
+			GroupID: group.ID,
+			Places:  snapshots[i].BudgetPlaces,
+			Entries: snapshots[i].Entries,
+		})
+
+		for _, entry := range snapshots[i].Entries {
+			if entry.HasConsent() {
+				prediction.consentOnly = true
+				break
+			}
+		}
+	}
+	if len(programs) == 0 {
+		return enrolmentPrediction{}, fmt.Errorf("не удалось загрузить ни одного конкурсного списка")
+	}
+
+	// Пока согласия никто не подавал, учитываем всех поступающих, иначе только подавших согласие.
+	// Сам пользователь учитывается всегда - он может подать согласие позже
+	prediction.outcome = admission.Simulate(programs, admission.Options{
+		RequireConsent: prediction.consentOnly,
+		AlwaysInclude:  strconv.Itoa(uniqueCode),
+	})
+	return prediction, nil
+}
+
+// predictionSnapshot возвращает список группы для моделирования. Списки групп, которые отслеживает
+// мониторинг, берутся из кэша или хранилища без загрузки, остальные загружаются, если в кэше нет свежей версии
+func (h *MgsuHandler) predictionSnapshot(group catalog.Group, monitored bool, followed bool) (models.Snapshot, bool) {
+	if monitored {
+		if snapshot, ok := h.cachedSnapshot(group); ok {
+			return snapshot, true
+		}
+		if snapshot, ok := h.lastGoodSnapshot(group.ID); ok {
+			return snapshot, true
+		}
+	}
+
+	maxAge := config.PREDICTION_LIST_TTL
+	if followed {
+		maxAge = config.LIST_CACHE_TTL
+	}
+	snapshot, err := h.fetchList(group, maxAge)
+	if err == nil {
+		return snapshot, true
+	}
+	if last, ok := h.lastGoodSnapshot(group.ID); ok {
+		return last, true
+	}
+	fmt.Printf("Прогноз: не удалось загрузить список %s: %v\n", group.ID, err)
+	return models.Snapshot{}, false
+}
+
+// formatPrediction форматирует прогноз зачисления для пользователя
+func (h *MgsuHandler) formatPrediction(uniqueCode int, followed []catalog.Group, prediction enrolmentPrediction) string {
+	code := strconv.Itoa(uniqueCode)
+
+	considered := "все поступающие (согласия на зачисление еще не подавались)"
+	if prediction.consentOnly {
+		considered = "поступающие с согласием на зачисление"
+	}
+
+	var lines []string
+	lines = append(lines,
+		fmt.Sprintf("🔮 Прогноз зачисления для кода %d", uniqueCode),
+		fmt.Sprintf("Моделирование распределения по приоритетам: %d бюджетных направлений, учтены %s.", len(prediction.groups), considered),
+		"",
+	)
+
+	if groupID, ok := prediction.outcome.Assigned[code]; ok {
+		result := fmt.Sprintf("✅ Вы проходите на: %s", prediction.groups[groupID].Title())
+		if priority := prediction.outcome.Priority[code]; priority != math.MaxInt32 {
+			result += fmt.Sprintf(" (приоритет %d)", priority)
+		}
+		lines = append(lines, result)
+	} else {
+		lines = append(lines, "❌ По прогнозу вы не проходите ни на одно бюджетное направление.")
+	}
+
+	lines = append(lines, "", "По выбранным направлениям:")
+	for _, group := range followed {
+		result, ok := prediction.outcome.Groups[group.ID]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("🎓 %s\nНе участвует в моделировании", group.Title()))
+			continue
+		}
+
+		block := fmt.Sprintf("🎓 %s\n", group.Title())
+		cutOff := result.CutOff()
+		switch cutOff.Status {
+		case models.CutOffKnown:
+			block += fmt.Sprintf("📊 Прогнозный проходной балл: %d", cutOff.Score)
+			if cutOff.Tied > 1 {
+				block += fmt.Sprintf(" (на последнее место претендуют %d поступающих с равными баллами)", cutOff.Tied)
+			}
+		case models.CutOffNoCompetition:
+			block += fmt.Sprintf("📊 Мест больше, чем прошедших (%d из %d) - конкурса нет", len(result.Admitted), result.Places)
+		default:
+			block += "📊 Прогнозный проходной балл: неизвестен"
+		}
+		for i, entry := range result.Admitted {
+			if strings.TrimSpace(entry.UniqueCode) == code {
+				block += fmt.Sprintf("\n🎯 Ваше место среди прошедших: %d/%d", i+1, result.Places)
+				break
+			}
+		}
+		lines = append(lines, block)
+	}
+
+	if prediction.failed > 0 {
+		lines = append(lines, "", fmt.Sprintf("⚠️ Не удалось загрузить списков: %d, прогноз может быть неточным.", prediction.failed))
+	}
+	lines = append(lines, "", "ℹ️ Это прогноз по текущим спискам, а не официальный результат зачисления.")
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bot/models"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Маршруты callback-данных кнопок выбора режима подсчета позиции
+const (
+	modeSetRoute = "mode:set" // mode:set:<режим>
+	// legacyModeRoute формат кнопок, отправленных до появления маршрутов: mode:<режим>
+	legacyModeRoute = "mode"
+)
+
+// positionModeOptions режимы подсчета позиции в порядке показа и их callback-значения
+var positionModeOptions = []struct {
+	mode  models.PositionMode
+	data  string
+	title string
+}{
+	{models.PositionOverall, "overall", "Среди всех поступающих"},
+	{models.PositionConsent, "consent", "Среди подавших согласие"},
+}
+
+// sendModeSelection предлагает выбрать, по какой позиции присылать уведомления
+func (h *MgsuHandler) sendModeSelection(chatID int64) {
+	msg := "🔔 По какой позиции присылать уведомления?\n\n" +
+		"• Среди всех поступающих с высшим проходным приоритетом\n" +
+		"• Только среди подавших согласие на зачисление (важно на последних этапах зачисления)"
+	h.botHandler.SendTextMessageWithMarkup(chatID, msg, h.modeSelectionMarkup(chatID))
+}
+
+// modeSelectionMarkup строит клавиатуру режимов, отмечая выбранный пользователем
+func (h *MgsuHandler) modeSelectionMarkup(chatID int64) tgbotapi.InlineKeyboardMarkup {
+	current := h.positionMode(chatID)
+
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, option := range positionModeOptions {
+		title := option.title
+		if option.mode == current {
+			title = "✅ " + title
+		}
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(title, CallbackData(modeSetRoute, option.data)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+}
+
+// handleModeSet сохраняет режим, выбранный нажатием на кнопку
+func (h *MgsuHandler) handleModeSet(callback *tgbotapi.CallbackQuery, args []string) string {
+	chatID := callback.Message.Chat.ID
+
+	for _, option := range positionModeOptions {
+		if option.data != firstArg(args) {
+			continue
+		}
+		if err := h.SetPositionMode(chatID, option.mode); err != nil {
+			fmt.Printf("Ошибка сохранения режима уведомлений %d: %v\n", chatID, err)
+			return "Не удалось сохранить режим, попробуйте позже"
+		}
+		h.botHandler.EditMessageReplyMarkup(chatID, callback.Message.MessageID, h.modeSelectionMarkup(chatID))
+		return "Уведомления: " + strings.ToLower(option.title)
+	}
+
+	return "Неизвестный режим"
+}
+
+// SetPositionMode сохраняет режим подсчета позиции для уведомлений пользователя
+func (h *MgsuHandler) SetPositionMode(chatID int64, mode models.PositionMode) error {
+	if err := h.store.SavePositionMode(chatID, mode); err != nil {
+		return err
+	}
+	h.mutex.Lock()
+	h.positionModes[chatID] = mode
+	h.mutex.Unlock()
+	return nil
+}
+
+// positionMode возвращает режим подсчета позиции пользователя (по умолчанию - среди всех)
+func (h *MgsuHandler) positionMode(chatID int64) models.PositionMode {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.positionModes[chatID]
+}
+
+// positionModeLabel поясняет, среди кого посчитана позиция
+func positionModeLabel(mode models.PositionMode) string {
+	if mode == models.PositionConsent {
+		return "среди подавших согласие"
+	}
+	return "среди всех"
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// subscriptionToggleRoute маршрут кнопок включения уведомлений по группе: sub:toggle:<groupID>
+const subscriptionToggleRoute = "sub:toggle"
+
+// sendSubscriptionSettings предлагает выбрать, по каким из отслеживаемых направлений присылать уведомления
+func (h *MgsuHandler) sendSubscriptionSettings(chatID int64) {
+	if len(h.followedGroups(chatID)) == 0 {
+		return
+	}
+	msg := "🔔 Уведомления по направлениям. Нажмите на направление, чтобы включить или отключить уведомления по нему."
+	h.botHandler.SendTextMessageWithMarkup(chatID, msg, h.subscriptionMarkup(chatID))
+}
+
+// subscriptionMarkup строит клавиатуру отслеживаемых групп с отметкой, включены ли по ним уведомления
+func (h *MgsuHandler) subscriptionMarkup(chatID int64) tgbotapi.InlineKeyboardMarkup {
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, group := range h.followedGroups(chatID) {
+		title := "🔔 " + group.Title()
+		if h.isGroupMuted(chatID, group.ID) {
+			title = "🔕 " + group.Title()
+		}
+		keyboard = append(keyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(title, CallbackData(subscriptionToggleRoute, group.ID)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(keyboard...)
+}
+
+// handleSubscriptionToggle включает или отключает уведомления по нажатой группе
+func (h *MgsuHandler) handleSubscriptionToggle(callback *tgbotapi.CallbackQuery, args []string) string {
+	chatID := callback.Message.Chat.ID
+
+	group, ok := h.catalog.Get(firstArg(args))
+	if !ok || !containsGroup(h.userGroupIDs(chatID), group.ID) {
+		return "Вы не отслеживаете это направление"
+	}
+
+	muted, err := h.ToggleMutedGroup(chatID, group.ID)
+	if err != nil {
+		fmt.Printf("Ошибка сохранения настроек уведомлений %d: %v\n", chatID, err)
+		return "Не удалось сохранить настройку, попробуйте позже"
+	}
+	h.botHandler.EditMessageReplyMarkup(chatID, callback.Message.MessageID, h.subscriptionMarkup(chatID))
+
+	if muted {
+		return "Уведомления отключены: " + group.Title()
+	}
+	return "Уведомления включены: " + group.Title()
+}
+
+// ToggleMutedGroup отключает уведомления по группе или включает их обратно.
+// Возвращает true, если уведомления теперь отключены
+func (h *MgsuHandler) ToggleMutedGroup(chatID int64, groupID string) (bool, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	current := h.mutedGroups[chatID]
+	var updated []string
+	muted := !containsGroup(current, groupID)
+	if muted {
+		updated = append(append(updated, current...), groupID)
+	} else {
+		for _, id := range current {
+			if id != groupID {
+				updated = append(updated, id)
+			}
+		}
+	}
+
+	if err := h.store.SaveMutedGroups(chatID, updated); err != nil {
+		return false, err
+	}
+	h.mutedGroups[chatID] = updated
+	return muted, nil
+}
+
+// isGroupMuted сообщает, отключил ли пользователь уведомления по группе
+func (h *MgsuHandler) isGroupMuted(chatID int64, groupID string) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return containsGroup(h.mutedGroups[chatID], groupID)
+}
+
+// userGroupIDs возвращает идентификаторы отслеживаемых пользователем групп
+func (h *MgsuHandler) userGroupIDs(chatID int64) []string {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.userGroups[chatID]
+}
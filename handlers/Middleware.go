@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Middleware оборачивает обработку обновления: может выполнить что-то до и после next или не вызывать его вовсе
+type Middleware func(next UpdateFunc) UpdateFunc
+
+// Use добавляет middleware. Первая добавленная выполняется первой и оборачивает все остальные
+func (b *BotHandler) Use(middlewares ...Middleware) {
+	b.middlewares = append(b.middlewares, middlewares...)
+}
+
+// pipeline собирает маршрутизацию и middleware в один обработчик
+func (b *BotHandler) pipeline() UpdateFunc {
+	handler := UpdateFunc(b.dispatch)
+	for i := len(b.middlewares) - 1; i >= 0; i-- {
+		handler = b.middlewares[i](handler)
+	}
+	return handler
+}
+
+// Recovery не дает панике в обработчике остановить бота: она записывается в лог вместе со стеком
+func Recovery() Middleware {
+	return func(next UpdateFunc) UpdateFunc {
+		return func(update *tgbotapi.Update) {
+			defer func() {
+				if err := recover(); err != nil {
+					log.Printf("[update %d] паника при обработке: %v\n%s", update.UpdateID, err, debug.Stack())
+				}
+			}()
+			next(update)
+		}
+	}
+}
+
+// Logging записывает в лог входящие сообщения и нажатия на кнопки
+func Logging() Middleware {
+	return func(next UpdateFunc) UpdateFunc {
+		return func(update *tgbotapi.Update) {
+			if update.Message != nil {
+				log.Printf("[update %d] [%s] %s", update.UpdateID, update.Message.From.UserName, update.Message.Text)
+			}
+			if update.CallbackQuery != nil {
+				log.Printf("[update %d] [%s] %s", update.UpdateID, update.CallbackQuery.From.UserName, update.CallbackQuery.Data)
+			}
+			next(update)
+		}
+	}
+}
+
+// Trace измеряет время обработки обновления и записывает в лог те, что обрабатывались дольше slow
+func Trace(slow time.Duration) Middleware {
+	return func(next UpdateFunc) UpdateFunc {
+		return func(update *tgbotapi.Update) {
+			started := time.Now()
+			defer func() {
+				if elapsed := time.Since(started); elapsed >= slow {
+					log.Printf("[update %d] %s обрабатывалось %v", update.UpdateID, updateKind(update), elapsed.Round(time.Millisecond))
+				}
+			}()
+			next(update)
+		}
+	}
+}
+
+// AccessControl пропускает только разрешенных пользователей. Пустой allowed разрешает всех,
+// кроме blocked; заблокированным бот не отвечает, остальным сообщает об ограничении доступа
+func AccessControl(b *BotHandler, allowed []int64, blocked []int64) Middleware {
+	allowedSet := make(map[int64]bool, len(allowed))
+	for _, id := range allowed {
+		allowedSet[id] = true
+	}
+	blockedSet := make(map[int64]bool, len(blocked))
+	for _, id := range blocked {
+		blockedSet[id] = true
+	}
+
+	return func(next UpdateFunc) UpdateFunc {
+		return func(update *tgbotapi.Update) {
+			user := updateUser(update)
+			if user == nil {
+				next(update)
+				return
+			}
+			if blockedSet[user.ID] {
+				return
+			}
+			if len(allowedSet) > 0 && !allowedSet[user.ID] {
+				b.replyLimited(update, "⛔ Доступ к боту ограничен.")
+				return
+			}
+			next(update)
+		}
+	}
+}
+
+// RateLimit ограничивает каждого пользователя limit обновлениями за period (с равномерным
+// восстановлением). Лишние обновления отбрасываются, о превышении пользователь узнает один раз
+func RateLimit(b *BotHandler, limit int, period time.Duration) Middleware {
+	type bucket struct {
+		tokens  float64
+		updated time.Time
+		warned  bool
+	}
+	var mutex sync.Mutex
+	buckets := make(map[int64]*bucket)
+	lastCleanup := time.Now()
+	refill := float64(limit) / period.Seconds()
+
+	allow := func(userID int64) (bool, bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		now := time.Now()
+		// Полностью восстановившиеся счетчики не отличаются от новых, поэтому периодически их удаляем
+		if now.Sub(lastCleanup) > period {
+			for id, bucket := range buckets {
+				if now.Sub(bucket.updated) > period {
+					delete(buckets, id)
+				}
+			}
+			lastCleanup = now
+		}
+
+		current, ok := buckets[userID]
+		if !ok {
+			current = &bucket{tokens: float64(limit), updated: now}
+			buckets[userID] = current
+		}
+		current.tokens = min(float64(limit), current.tokens+now.Sub(current.updated).Seconds()*refill)
+		current.updated = now
+
+		if current.tokens < 1 {
+			warn := !current.warned
+			current.warned = true
+			return false, warn
+		}
+		current.tokens--
+		current.warned = false
+		return true, false
+	}
+
+	return func(next UpdateFunc) UpdateFunc {
+		return func(update *tgbotapi.Update) {
+			user := updateUser(update)
+			if user == nil || limit <= 0 {
+				next(update)
+				return
+			}
+			ok, warn := allow(user.ID)
+			if ok {
+				next(update)
+				return
+			}
+			if warn {
+				b.replyLimited(update, "⏳ Слишком много запросов, подождите немного.")
+			} else if update.CallbackQuery != nil {
+				// На нажатие нужно ответить, иначе кнопка останется в состоянии загрузки
+				b.AnswerCallback(update.CallbackQuery.ID, "")
+			}
+		}
+	}
+}
+
+// replyLimited сообщает пользователю, что его обновление не будет обработано
+func (b *BotHandler) replyLimited(update *tgbotapi.Update, text string) {
+	if update.CallbackQuery != nil {
+		b.AnswerCallback(update.CallbackQuery.ID, text)
+		return
+	}
+	if update.Message != nil {
+		b.SendTextMessage(update.Message.Chat.ID, text)
+	}
+}
+
+// updateUser возвращает автора сообщения или нажатия на кнопку
+func updateUser(update *tgbotapi.Update) *tgbotapi.User {
+	switch {
+	case update.Message != nil:
+		return update.Message.From
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.From
+	}
+	return nil
+}
+
+// updateKind тип обновления для логов и метрик
+func updateKind(update *tgbotapi.Update) string {
+	switch {
+	case update.Message != nil && update.Message.IsCommand():
+		return "command"
+	case update.Message != nil:
+		return "message"
+	case update.CallbackQuery != nil:
+		return "callback"
+	}
+	return "other"
+}
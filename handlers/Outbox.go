@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Ограничения Telegram на исходящие сообщения
+const (
+	// globalSendRate не более стольких запросов в секунду на всех пользователей
+	globalSendRate = 30
+	// chatSendInterval в среднем не чаще одного сообщения за этот интервал в один чат
+	chatSendInterval = time.Second
+	// chatBurst сколько сообщений подряд можно отправить в чат, который давно ничего не получал
+	chatBurst = 3
+	// sendWorkers сколько запросов выполняется одновременно
+	sendWorkers = 4
+)
+
+// Очереди исходящих сообщений: интерактивные ответы отправляются раньше массовых уведомлений
+const (
+	laneInteractive = iota
+	laneBroadcast
+	laneCount
+)
+
+// laneCapacity размер очереди, при заполнении отправители ждут освобождения места
+var laneCapacity = [laneCount]int{laneInteractive: 100, laneBroadcast: 1000}
+
+// laneChatTokens сколько запаса чата нужно, чтобы отправить в него запрос из очереди. Массовые уведомления
+// ждут полного запаса, поэтому идут не чаще раза в интервал и не отнимают у ответов пользователю серию сообщений
+var laneChatTokens = [laneCount]float64{laneInteractive: 1, laneBroadcast: chatBurst}
+
+type sendJob struct {
+	lane      int
+	chatID    int64
+	request   tgbotapi.Chattable
+	attempt   int       // Номер последней попытки отправки
+	notBefore time.Time // Повтор после временной ошибки не раньше этого времени
+	done      chan error
+}
+
+// chatBudget запас сообщений чата (token bucket)
+type chatBudget struct {
+	tokens  float64
+	updated time.Time
+}
+
+// outbox очередь исходящих запросов, общая для всех копий BotHandler
+type outbox struct {
+	mutex     sync.Mutex
+	lanes     [laneCount][]*sendJob
+	slots     [laneCount]chan struct{} // Свободные места в очередях
+	chats     map[int64]*chatBudget    // chatID -> запас сообщений чата
+	sending   map[int64]bool           // chatID -> запрос в чат уже отправляется или ждет повтора
+	lastPrune time.Time
+	tokens    float64 // Глобальный лимит запросов (token bucket)
+	updated   time.Time
+	wake      chan struct{}
+	jobs      chan *sendJob
+}
+
+// newOutbox создает очередь и запускает отправку через deliver. Если deliver возвращает паузу,
+// запрос возвращается в очередь и повторяется после нее, не занимая отправителя
+func newOutbox(deliver func(chatID int64, request tgbotapi.Chattable, attempt int) (time.Duration, error)) *outbox {
+	o := emptyOutbox(time.Now())
+	go o.schedule()
+	for i := 0; i < sendWorkers; i++ {
+		go func() {
+			for job := range o.jobs {
+				job.attempt++
+				retryAfter, err := deliver(job.chatID, job.request, job.attempt)
+				if retryAfter > 0 {
+					o.retry(job, time.Now().Add(retryAfter))
+					continue
+				}
+				o.finish(job)
+				job.done <- err
+			}
+		}()
+	}
+	return o
+}
+
+// emptyOutbox создает очередь без отправителей, now - время, с которого отсчитываются лимиты
+func emptyOutbox(now time.Time) *outbox {
+	o := &outbox{
+		chats:     make(map[int64]*chatBudget),
+		sending:   make(map[int64]bool),
+		lastPrune: now,
+		tokens:    globalSendRate,
+		updated:   now,
+		wake:      make(chan struct{}, 1),
+		jobs:      make(chan *sendJob),
+	}
+	for lane := range o.slots {
+		o.slots[lane] = make(chan struct{}, laneCapacity[lane])
+	}
+	return o
+}
+
+// enqueue ставит запрос в очередь и ждет результата отправки.
+// chatID 0 означает запрос без чата (ответ на callback), он не ограничивается по чату
+func (o *outbox) enqueue(lane int, chatID int64, request tgbotapi.Chattable) error {
+	o.slots[lane] <- struct{}{}
+
+	job := &sendJob{lane: lane, chatID: chatID, request: request, done: make(chan error, 1)}
+	o.push(job)
+	o.notify()
+	return <-job.done
+}
+
+// push ставит запрос в конец очереди, место в ней должно быть уже занято
+func (o *outbox) push(job *sendJob) {
+	o.mutex.Lock()
+	o.lanes[job.lane] = append(o.lanes[job.lane], job)
+	o.mutex.Unlock()
+}
+
+// retry возвращает запрос в начало его очереди, чтобы повторить не раньше notBefore.
+// До повтора следующие запросы в тот же чат не отправляются, чтобы не нарушить порядок сообщений
+func (o *outbox) retry(job *sendJob, notBefore time.Time) {
+	job.notBefore = notBefore
+	o.mutex.Lock()
+	delete(o.sending, job.chatID)
+	o.lanes[job.lane] = append([]*sendJob{job}, o.lanes[job.lane]...)
+	o.mutex.Unlock()
+
+	o.notify()
+}
+
+// finish отмечает, что запрос отправлен или окончательно не удался, и освобождает чат и место в очереди
+func (o *outbox) finish(job *sendJob) {
+	o.mutex.Lock()
+	delete(o.sending, job.chatID)
+	o.mutex.Unlock()
+	<-o.slots[job.lane]
+
+	o.notify()
+}
+
+func (o *outbox) notify() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// schedule передает запросы отправителям, соблюдая ограничения, и ждет, если отправлять пока нечего
+func (o *outbox) schedule() {
+	timer := time.NewTimer(0)
+	for {
+		o.mutex.Lock()
+		job, wait := o.next(time.Now())
+		o.mutex.Unlock()
+
+		if job != nil {
+			o.jobs <- job
+			continue
+		}
+
+		if wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-o.wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+		} else {
+			<-o.wake
+		}
+	}
+}
+
+// next выбирает первый запрос, который можно отправить сейчас: сначала из интерактивной очереди,
+// пропуская отложенные повторы, чаты, запас которых исчерпан, и чаты, запрос в которые еще отправляется.
+// Запросы в один чат уходят по одному в порядке очереди. Если отправить нечего, возвращает,
+// сколько ждать (0 - до нового запроса или завершения отправки)
+func (o *outbox) next(now time.Time) (*sendJob, time.Duration) {
+	o.tokens = min(globalSendRate, o.tokens+now.Sub(o.updated).Seconds()*globalSendRate)
+	o.updated = now
+	if o.tokens < 1 {
+		return nil, ceilDuration((1 - o.tokens) / globalSendRate * float64(time.Second))
+	}
+
+	// Чаты с полным запасом не отличаются от новых
+	if now.Sub(o.lastPrune) > time.Minute {
+		for chatID, budget := range o.chats {
+			if budget.refill(now) >= chatBurst {
+				delete(o.chats, chatID)
+			}
+		}
+		o.lastPrune = now
+	}
+
+	var wait time.Duration
+	later := func(d time.Duration) {
+		if wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	var held map[int64]bool // Чаты, более ранний запрос в которые пока нельзя отправить
+	hold := func(chatID int64) {
+		if held == nil {
+			held = make(map[int64]bool)
+		}
+		held[chatID] = true
+	}
+	for lane := range o.lanes {
+		for i, job := range o.lanes[lane] {
+			if job.chatID != 0 && (o.sending[job.chatID] || held[job.chatID]) {
+				continue
+			}
+			if now.Before(job.notBefore) {
+				later(job.notBefore.Sub(now))
+				hold(job.chatID)
+				continue
+			}
+			if job.chatID != 0 {
+				budget, ok := o.chats[job.chatID]
+				if !ok {
+					budget = &chatBudget{tokens: chatBurst, updated: now}
+					o.chats[job.chatID] = budget
+				}
+				if lack := laneChatTokens[lane] - budget.refill(now); lack > 0 {
+					later(ceilDuration(lack * float64(chatSendInterval)))
+					hold(job.chatID)
+					continue
+				}
+				budget.tokens--
+				o.sending[job.chatID] = true
+			}
+			o.lanes[lane] = append(o.lanes[lane][:i], o.lanes[lane][i+1:]...)
+			o.tokens--
+			return job, 0
+		}
+	}
+	return nil, wait
+}
+
+// ceilDuration округляет паузу вверх: иначе из-за погрешности она может оказаться нулевой,
+// и очередь будет ждать нового запроса вместо уже стоящих в ней
+func ceilDuration(nanoseconds float64) time.Duration {
+	return time.Duration(math.Ceil(nanoseconds))
+}
+
+// refill пополняет запас чата на момент now и возвращает его
+func (c *chatBudget) refill(now time.Time) float64 {
+	c.tokens = min(chatBurst, c.tokens+float64(now.Sub(c.updated))/float64(chatSendInterval))
+	c.updated = now
+	return c.tokens
+}
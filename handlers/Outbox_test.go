@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, time.July, 20, 12, 0, 0, 0, time.UTC)
+
+func push(o *outbox, lane int, chatID int64) *sendJob {
+	job := &sendJob{lane: lane, chatID: chatID, done: make(chan error, 1)}
+	o.slots[lane] <- struct{}{}
+	o.push(job)
+	return job
+}
+
+// take забирает очередной запрос и сразу завершает его отправку
+func take(t *testing.T, o *outbox, now time.Time) *sendJob {
+	t.Helper()
+	job, wait := o.next(now)
+	if job == nil {
+		t.Fatalf("нет запроса к отправке, ожидание %v", wait)
+	}
+	o.finish(job)
+	return job
+}
+
+func TestOutboxGlobalRate(t *testing.T) {
+	o := emptyOutbox(testNow)
+	for chatID := int64(1); chatID <= globalSendRate+5; chatID++ {
+		push(o, laneBroadcast, chatID)
+	}
+
+	for i := 0; i < globalSendRate; i++ {
+		take(t, o, testNow)
+	}
+	job, wait := o.next(testNow)
+	if job != nil {
+		t.Fatalf("отправлено больше %d запросов за секунду", globalSendRate)
+	}
+	if want := time.Second / globalSendRate; wait < want-time.Millisecond || wait > want+time.Millisecond {
+		t.Errorf("ожидание %v, ожидалось около %v", wait, want)
+	}
+
+	if job := take(t, o, testNow.Add(wait)); job.chatID != globalSendRate+1 {
+		t.Errorf("отправлен запрос в чат %d, ожидался %d", job.chatID, globalSendRate+1)
+	}
+}
+
+func TestOutboxChatBurst(t *testing.T) {
+	tests := []struct {
+		name  string
+		lane  int
+		burst int // Сколько запросов в новый чат уходит сразу
+	}{
+		{name: "интерактивные ответы сериями", lane: laneInteractive, burst: chatBurst},
+		{name: "уведомления не чаще раза в интервал", lane: laneBroadcast, burst: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := emptyOutbox(testNow)
+			for i := 0; i < chatBurst+2; i++ {
+				push(o, tt.lane, 1)
+			}
+
+			for i := 0; i < tt.burst; i++ {
+				take(t, o, testNow)
+			}
+			job, wait := o.next(testNow)
+			if job != nil {
+				t.Fatalf("в чат ушло больше %d запросов подряд", tt.burst)
+			}
+			if wait != chatSendInterval {
+				t.Errorf("ожидание %v, ожидалось %v", wait, chatSendInterval)
+			}
+
+			take(t, o, testNow.Add(chatSendInterval))
+			if job, _ := o.next(testNow.Add(chatSendInterval)); job != nil {
+				t.Error("после паузы в чат ушло больше одного запроса")
+			}
+		})
+	}
+}
+
+func TestOutboxLanePriority(t *testing.T) {
+	o := emptyOutbox(testNow)
+	broadcast := push(o, laneBroadcast, 1)
+	interactive := push(o, laneInteractive, 2)
+	callback := push(o, laneInteractive, 0)
+
+	for _, want := range []*sendJob{interactive, callback, broadcast} {
+		if job := take(t, o, testNow); job != want {
+			t.Errorf("отправлен запрос в чат %d из очереди %d, ожидался в чат %d из очереди %d", job.chatID, job.lane, want.chatID, want.lane)
+		}
+	}
+}
+
+func TestOutboxChatOrder(t *testing.T) {
+	t.Run("следующий запрос ждет отправки предыдущего", func(t *testing.T) {
+		o := emptyOutbox(testNow)
+		first := push(o, laneInteractive, 1)
+		second := push(o, laneInteractive, 1)
+		other := push(o, laneInteractive, 2)
+
+		if job, _ := o.next(testNow); job != first {
+			t.Fatal("первым должен уйти первый запрос в чат")
+		}
+		if job, _ := o.next(testNow); job != other {
+			t.Fatal("пока первый запрос отправляется, должен уйти запрос в другой чат")
+		}
+		o.finish(first)
+		if job, _ := o.next(testNow); job != second {
+			t.Fatal("после отправки первого запроса должен уйти второй")
+		}
+	})
+
+	t.Run("повтор не обгоняют следующие запросы в чат", func(t *testing.T) {
+		o := emptyOutbox(testNow)
+		first := push(o, laneInteractive, 1)
+		second := push(o, laneInteractive, 1)
+		broadcast := push(o, laneBroadcast, 1)
+
+		if job, _ := o.next(testNow); job != first {
+			t.Fatal("первым должен уйти первый запрос в чат")
+		}
+		o.retry(first, testNow.Add(2*time.Second))
+
+		job, wait := o.next(testNow.Add(time.Second))
+		if job != nil {
+			t.Fatalf("до повтора ушел запрос из очереди %d", job.lane)
+		}
+		if wait != time.Second {
+			t.Errorf("ожидание %v, ожидалось до повтора через %v", wait, time.Second)
+		}
+
+		for _, want := range []*sendJob{first, second} {
+			if job := take(t, o, testNow.Add(2*time.Second)); job != want {
+				t.Fatal("запросы в чат ушли не в порядке очереди")
+			}
+		}
+		if job := take(t, o, testNow.Add(5*time.Second)); job != broadcast {
+			t.Fatal("уведомление должно уйти после ответов")
+		}
+	})
+}
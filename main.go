@@ -1,8 +1,16 @@
 package main
 
 import (
+	"bot/catalog"
 	"bot/config"
+	"bot/fetcher"
 	"bot/handlers"
+	"bot/sources"
+	"bot/storage"
+	"bot/webhook"
+	"fmt"
+	"net/http"
+	"os"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -13,18 +21,110 @@ func main() {
 		panic(err)
 	}
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 50
+	store, err := storage.NewBoltStorage(config.DB_PATH)
+	if err != nil {
+		panic(err)
+	}
+	defer store.Close()
 
-	updates := bot.GetUpdatesChan(u)
+	page_fetcher, err := fetcher.New(fetcher.Config{
+		Timeout:     config.HTTP_TIMEOUT,
+		MaxRetries:  config.HTTP_MAX_RETRIES,
+		BaseBackoff: config.HTTP_BACKOFF,
+		MaxBackoff:  config.HTTP_MAX_BACKOFF,
+		UserAgent:   config.HTTP_USER_AGENT,
+		ProxyURL:    config.HTTP_PROXY_URL,
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	// Источники конкурсных списков. Адаптеры других вузов регистрируются здесь же,
+	// а их группы указывают имя источника в поле source каталога
+	list_sources := sources.NewRegistry(sources.NewMgsuSource(page_fetcher))
+
+	// Каталог конкурсных групп берем из файла, если он есть, затем из хранилища,
+	// иначе используем встроенный. Дальше он обновляется обходом индекса МГСУ
+	groups := catalog.Default()
+	if _, err := os.Stat(config.GROUPS_PATH); err == nil {
+		groups, err = catalog.LoadFile(config.GROUPS_PATH)
+		if err != nil {
+			panic(err)
+		}
+	} else if saved, err := store.LoadGroups(); err != nil {
+		panic(err)
+	} else if len(saved) > 0 {
+		groups = saved
+	}
+	groups_catalog := catalog.New(groups)
+
+	if config.CATALOG_INDEX_URL != "" {
+		crawler := catalog.NewCrawler(config.CATALOG_INDEX_URL, page_fetcher)
+		crawler.Start(groups_catalog, config.CATALOG_REFRESH_INTERVAL, store.SaveGroups)
+	}
+
+	var updates tgbotapi.UpdatesChannel
+	switch config.BOT_MODE {
+	case "webhook":
+		if config.WEBHOOK_URL == "" || config.WEBHOOK_SECRET == "" {
+			panic("для режима webhook нужны WEBHOOK_URL и WEBHOOK_SECRET")
+		}
+		// Сначала открываем порт, иначе первые обновления после регистрации получат отказ в соединении
+		server := webhook.New(config.WEBHOOK_PATH, config.WEBHOOK_SECRET)
+		if err := server.Start(config.WEBHOOK_LISTEN_ADDR); err != nil {
+			panic(err)
+		}
+		if err := webhook.Register(bot, config.WEBHOOK_URL, config.WEBHOOK_SECRET); err != nil {
+			panic(err)
+		}
+		updates = server.Updates()
+	case "polling":
+		// Пока установлен вебхук, getUpdates не работает
+		if err := webhook.Remove(bot); err != nil {
+			panic(err)
+		}
+
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 50
+
+		updates = bot.GetUpdatesChan(u)
+	default:
+		panic("неизвестный BOT_MODE: " + config.BOT_MODE)
+	}
 
 	bot_handler := handlers.NewBotHandler(&updates, bot)
 
+	// Паника перехватывается снаружи всего остального, а ограничения доступа проверяются последними,
+	// чтобы отброшенные обновления тоже попадали в лог и метрики
+	metrics := handlers.NewMetrics()
+	bot_handler.Use(
+		handlers.Recovery(),
+		handlers.Logging(),
+		handlers.Trace(config.SLOW_UPDATE_THRESHOLD),
+		metrics.Middleware(),
+		handlers.AccessControl(&bot_handler, config.ALLOWED_USER_IDS, config.BLOCKED_USER_IDS),
+		handlers.RateLimit(&bot_handler, config.RATE_LIMIT, config.RATE_LIMIT_PERIOD),
+	)
+	if config.METRICS_ADDR != "" {
+		go func() {
+			if err := http.ListenAndServe(config.METRICS_ADDR, metrics); err != nil {
+				panic(fmt.Sprintf("ошибка сервера метрик: %v", err))
+			}
+		}()
+	}
+
 	command_handler := handlers.NewCommandHandler(&bot_handler)
-	mgsu_handler := handlers.NewMgsuHandler(&bot_handler)
+	callback_handler := handlers.NewCallbackHandler(&bot_handler)
+	mgsu_handler, err := handlers.NewMgsuHandler(&bot_handler, store, groups_catalog, list_sources)
+	if err != nil {
+		panic(err)
+	}
+	mgsu_handler.RegisterCallbacks(&callback_handler)
+	bot_handler.OnChatBlocked(mgsu_handler.HandleChatBlocked)
 
-	bot_handler.AddHandler(command_handler.CommandHandler)
-	bot_handler.AddHandler(mgsu_handler.MgsuHandler)
+	command_handler.RegisterRoutes(&bot_handler)
+	callback_handler.RegisterRoutes(&bot_handler)
+	mgsu_handler.RegisterRoutes(&bot_handler)
 
 	// Запускаем мониторинг МГСУ
 	mgsu_handler.StartMonitoring()
@@ -0,0 +1,114 @@
+package models
+
+// InfoDiff описывает изменения позиции поступающего между двумя версиями списка
+type InfoDiff struct {
+	PreviousPosition     int
+	CurrentPosition      int
+	CurrentPlaces        int // Количество мест, от которого считается текущая позиция
+	PreviousMinScore     int
+	CurrentMinScore      int
+	PreviousCutOffStatus CutOffStatus
+	CurrentCutOffStatus  CutOffStatus
+	PreviousBudgetPlaces int
+	CurrentBudgetPlaces  int
+	ConsentSubmitted     int // Сколько поступающих, стоявших выше и в прошлой версии, подали согласие
+	ConsentWithdrawn     int // Сколько поступающих, стоявших выше и в прошлой версии, отозвали согласие
+	MovedAbove           int // Сколько поступающих оказались выше, хотя раньше были ниже или отсутствовали
+	MovedBelow           int // Сколько поступающих перестали быть выше (опустились ниже или выбыли)
+	PreviousHighPassing  bool
+	CurrentHighPassing   bool // Это направление - высший проходной приоритет поступающего
+}
+
+// Diff сравнивает предыдущую и текущую информацию о позиции поступающего в выбранном режиме подсчета
+func Diff(previous, current StudentInfo, mode PositionMode) InfoDiff {
+	previousPosition, _ := previous.PositionIn(mode)
+	currentPosition, currentPlaces := current.PositionIn(mode)
+	// Предыдущая версия сохранена до появления этого режима или позиция считалась среди других
+	// поступающих (изменился высший проходной приоритет) - сравнивать не с чем
+	comparable := previous.NotHighPassing == current.NotHighPassing
+	if previousPosition == 0 || !comparable {
+		previousPosition = currentPosition
+	}
+
+	diff := InfoDiff{
+		PreviousPosition:     previousPosition,
+		CurrentPosition:      currentPosition,
+		CurrentPlaces:        currentPlaces,
+		PreviousMinScore:     previous.MinPassingScore,
+		CurrentMinScore:      current.MinPassingScore,
+		PreviousCutOffStatus: previous.CutOffStatus,
+		CurrentCutOffStatus:  current.CutOffStatus,
+		PreviousBudgetPlaces: previous.BudgetPlaces,
+		CurrentBudgetPlaces:  current.BudgetPlaces,
+		PreviousHighPassing:  !previous.NotHighPassing,
+		CurrentHighPassing:   !current.NotHighPassing,
+	}
+
+	// В версиях без списка стоящих выше нельзя отличить смену согласия от перемещения по списку
+	if previous.Above == nil || current.Above == nil || !comparable {
+		return diff
+	}
+
+	consentBefore := codeSet(previous.ConsentAbove)
+	consentNow := codeSet(current.ConsentAbove)
+	aboveBefore := codeSet(previous.Above)
+	aboveNow := codeSet(current.Above)
+
+	// Согласие сравниваем только у тех, кто стоит выше в обеих версиях, остальные - перемещения
+	for code := range aboveNow {
+		if !aboveBefore[code] {
+			diff.MovedAbove++
+			continue
+		}
+		switch {
+		case consentNow[code] && !consentBefore[code]:
+			diff.ConsentSubmitted++
+		case !consentNow[code] && consentBefore[code]:
+			diff.ConsentWithdrawn++
+		}
+	}
+	for code := range aboveBefore {
+		if !aboveNow[code] {
+			diff.MovedBelow++
+		}
+	}
+
+	return diff
+}
+
+func codeSet(codes []string) map[string]bool {
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}
+
+func (d InfoDiff) PositionChanged() bool {
+	return d.PreviousPosition != d.CurrentPosition
+}
+
+func (d InfoDiff) MinScoreChanged() bool {
+	return d.PreviousMinScore != d.CurrentMinScore || d.PreviousCutOffStatus != d.CurrentCutOffStatus
+}
+
+func (d InfoDiff) BudgetPlacesChanged() bool {
+	return d.PreviousBudgetPlaces != d.CurrentBudgetPlaces
+}
+
+func (d InfoDiff) ConsentChanged() bool {
+	return d.ConsentSubmitted != 0 || d.ConsentWithdrawn != 0
+}
+
+func (d InfoDiff) HighPassingChanged() bool {
+	return d.PreviousHighPassing != d.CurrentHighPassing
+}
+
+func (d InfoDiff) AboveChanged() bool {
+	return d.MovedAbove != 0 || d.MovedBelow != 0
+}
+
+// HasChanges проверяет, изменилось ли что-нибудь для поступающего
+func (d InfoDiff) HasChanges() bool {
+	return d.PositionChanged() || d.MinScoreChanged() || d.BudgetPlacesChanged() || d.ConsentChanged() || d.AboveChanged() || d.HighPassingChanged()
+}
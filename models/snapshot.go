@@ -0,0 +1,60 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// creationLayout формат даты и времени формирования списка на сайте МГСУ
+const creationLayout = "02.01.2006 15:04:05"
+
+// Snapshot сохраненная версия конкурсного списка одной группы
+type Snapshot struct {
+	GroupID      string         // Идентификатор конкурсной группы
+	CreationDate string         // Дата формирования списка
+	CreationTime string         // Время формирования списка
+	BudgetPlaces int            // Количество бюджетных мест
+	FetchedAt    time.Time      // Когда список был загружен ботом
+	Entries      []StudentEntry // Все строки таблицы
+}
+
+// CreatedAt возвращает момент формирования списка. Если дату не удалось разобрать,
+// используется время загрузки
+func (s Snapshot) CreatedAt() time.Time {
+	created, err := time.ParseInLocation(creationLayout, s.CreationDate+" "+s.CreationTime, moscow)
+	if err != nil {
+		return s.FetchedAt
+	}
+	return created
+}
+
+// Key возвращает сортируемый ключ версии списка внутри группы
+func (s Snapshot) Key() string {
+	return SnapshotKey(s.CreatedAt())
+}
+
+// SnapshotKey возвращает ключ версии списка для момента времени, позволяя сравнивать ключи с датами
+func SnapshotKey(t time.Time) string {
+	return t.In(moscow).Format("20060102150405")
+}
+
+// ContentHash возвращает хэш содержимого списка без времени загрузки. По нему видно правки,
+// при которых МГСУ не обновил время формирования
+func (s Snapshot) ContentHash() string {
+	data, err := json.Marshal(struct {
+		CreationDate string
+		CreationTime string
+		BudgetPlaces int
+		Entries      []StudentEntry
+	}{s.CreationDate, s.CreationTime, s.BudgetPlaces, s.Entries})
+	if err != nil {
+		return ""
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// moscow часовой пояс, в котором МГСУ публикует время формирования списков
+var moscow = time.FixedZone("MSK", 3*60*60)
@@ -0,0 +1,130 @@
+package models
+
+import "strings"
+
+// StudentInfo представляет информацию о позиции студента в конкурсном списке
+type StudentInfo struct {
+	BudgetPlaces    int               // Количество бюджетных мест
+	GeneralPlaces   int               // Мест по общему конкурсу, оставшихся после БВИ и преимущественного права ч. 10
+	Position        string            // Позиция в формате "69/107"
+	PositionNumber  int               // Позиция среди поступающих с высшим проходным приоритетом по общему конкурсу
+	PositionPlaces  int               // Количество мест, от которого считается позиция
+	ConsentPosition string            // Позиция только среди подавших согласие, в формате "41/107"
+	ConsentNumber   int               // Позиция только среди подавших согласие
+	ConsentPlaces   int               // Количество мест, от которого считается позиция среди подавших согласие
+	Category        ApplicantCategory // Категория самого поступающего
+	BVIAbove        int               // Сколько поступающих без вступительных испытаний выше
+	PPRAbove        int               // Сколько поступающих с преимущественным правом (ч. 9 и ч. 10) выше
+	TotalScore      int               // Сумма баллов поступающего
+	MinPassingScore int               // Минимальный проходной балл, если CutOffStatus == CutOffKnown
+	CutOffStatus    CutOffStatus      // Удалось ли определить проходной балл
+	TiedAtCutOff    int               // Сколько поступающих с равными показателями делят последнее проходное место
+	Chance          float64           // Оценка шанса на бюджетное место от 0 до 1, если ChanceKnown
+	ChanceKnown     bool
+	CreationDate    string   // Дата создания списка
+	CreationTime    string   // Время создания списка
+	Direction       string   // Направление обучения
+	ConsentAbove    []string // Уникальные коды поступающих выше по списку, подавших согласие на зачисление
+	Above           []string // Уникальные коды всех поступающих выше по списку (nil в версиях, сохраненных до появления поля)
+	Stale           bool     // Посчитано по сохраненной версии, потому что текущая страница не прошла проверку структуры
+	NotHighPassing  bool     // Нет отметки "Это высший проходной приоритет": позиция посчитана среди всех поступающих списка
+	PassingPriority string   // Высший проходной приоритет поступающего, если он проходит на другое направление
+}
+
+// PositionIn возвращает позицию и количество мест в выбранном режиме подсчета
+func (i StudentInfo) PositionIn(mode PositionMode) (int, int) {
+	if mode == PositionConsent {
+		return i.ConsentNumber, i.ConsentPlaces
+	}
+	return i.PositionNumber, i.PositionPlaces
+}
+
+// PositionMode режим подсчета позиции, по которому пользователю приходят уведомления
+type PositionMode int
+
+const (
+	PositionOverall PositionMode = iota // Среди всех поступающих с высшим проходным приоритетом
+	PositionConsent                     // Только среди подавших согласие на зачисление
+)
+
+// CutOffStatus показывает, удалось ли определить проходной балл
+type CutOffStatus int
+
+const (
+	CutOffKnown         CutOffStatus = iota // Все места заняты, проходной балл - балл последнего проходящего
+	CutOffNoCompetition                     // Поступающих меньше, чем мест, конкурса нет
+	CutOffUnknown                           // Баллы на границе не удалось разобрать
+)
+
+// StudentEntry представляет запись о студенте в таблице
+type StudentEntry struct {
+	Number                string
+	UniqueCode            string
+	TotalScore            string
+	SubjectScore          string
+	Math                  string
+	IT                    string
+	Russian               string
+	GeneralAchievements   string
+	AdmissionConsent      string
+	Priority              string
+	MainHighPriority      string
+	IsMainHighPriority    string
+	HighPassingPriority   string
+	IsHighPassingPriority string
+	PPR9                  string
+	PPR10                 string
+	BVIBasis              string
+	Subjects              []SubjectScore    // Баллы по вступительным испытаниям в порядке столбцов страницы (в порядке приоритета)
+	Extra                 map[string]string // Столбцы, которые не удалось сопоставить с полями: заголовок -> значение
+}
+
+// SubjectScore балл по одному вступительному испытанию
+type SubjectScore struct {
+	Name  string // Заголовок столбца, например "Матем / ЧиИГ"
+	Score string
+}
+
+// ApplicantCategory категория, по которой поступающий претендует на место
+type ApplicantCategory int
+
+const (
+	CategoryGeneral      ApplicantCategory = iota // Общий конкурс
+	CategoryPreferential                          // Преимущественное право (ч. 9 ст. 71): выше при равенстве всех баллов
+	CategoryPriority                              // Преимущественное право (ч. 10 ст. 71): занимает место раньше общего конкурса
+	CategoryBVI                                   // Без вступительных испытаний: занимает место первым
+)
+
+// Category возвращает категорию поступающего. Если отмечено несколько, возвращается старшая
+func (e StudentEntry) Category() ApplicantCategory {
+	switch {
+	case hasMark(e.BVIBasis):
+		return CategoryBVI
+	case hasMark(e.PPR10):
+		return CategoryPriority
+	case hasMark(e.PPR9):
+		return CategoryPreferential
+	}
+	return CategoryGeneral
+}
+
+// TakesSeatFirst проверяет, занимает ли поступающий место раньше общего конкурса (БВИ и ч. 10)
+func (c ApplicantCategory) TakesSeatFirst() bool {
+	return c == CategoryBVI || c == CategoryPriority
+}
+
+// hasMark проверяет, что в ячейке что-то отмечено: основание, галочка или "да"
+func hasMark(value string) bool {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "", "-", "—", "нет", "0":
+		return false
+	}
+	return true
+}
+
+// HasConsent проверяет, подал ли поступающий согласие на зачисление
+func (e StudentEntry) HasConsent() bool {
+	consent := strings.TrimSpace(e.AdmissionConsent)
+	return strings.Contains(consent, "✓") || strings.EqualFold(consent, "да")
+}
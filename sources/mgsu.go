@@ -0,0 +1,226 @@
+package sources
+
+import (
+	"bot/catalog"
+	"bot/fetcher"
+	"bot/models"
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// MgsuName имя источника конкурсных списков МГСУ
+const MgsuName = "mgsu"
+
+// MgsuSource разбирает HTML-страницы конкурсных списков с сайта mgsu.ru
+type MgsuSource struct {
+	fetcher *fetcher.Fetcher
+}
+
+func NewMgsuSource(pageFetcher *fetcher.Fetcher) *MgsuSource {
+	return &MgsuSource{fetcher: pageFetcher}
+}
+
+func (s *MgsuSource) Name() string {
+	return MgsuName
+}
+
+func (s *MgsuSource) Fetch(ctx context.Context, group catalog.Group, validators fetcher.Validators) (*fetcher.Response, error) {
+	return s.fetcher.GetConditional(ctx, group.URL, validators)
+}
+
+// Parse разбирает страницу конкурсного списка и проверяет ее структуру: метаданные
+// (количество мест, дата и время формирования, конкурсная группа) и заголовки таблицы
+func (s *MgsuSource) Parse(group catalog.Group, body []byte) (List, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return List{}, fmt.Errorf("ошибка парсинга HTML: %v", err)
+	}
+
+	var problems, warnings []string
+
+	budgetPlaces, ok := s.extractBudgetPlaces(doc)
+	if !ok {
+		problems = append(problems, `не найдено количество мест ("Всего мест: N.")`)
+	}
+
+	creationDate, creationTime := s.extractCreationDateTime(doc)
+	if creationDate == "" || creationTime == "" {
+		problems = append(problems, "не найдены дата и время формирования списка")
+	} else if _, err := time.Parse("02.01.2006 15:04:05", creationDate+" "+creationTime); err != nil {
+		problems = append(problems, fmt.Sprintf("некорректные дата и время формирования: %q %q", creationDate, creationTime))
+	}
+
+	// Проверяем, что на странице именно та группа, которую мы ожидаем
+	if groupName, ok := s.extractGroupName(doc); !ok {
+		warnings = append(warnings, `не найдена ячейка "Конкурсная группа"`)
+	} else if group.Code != "" && !strings.Contains(groupName, group.Code) {
+		problems = append(problems, fmt.Sprintf("на странице другая конкурсная группа: %q", groupName))
+	}
+
+	students, err := s.parseStudentTable(doc)
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		return List{Warnings: warnings}, &LayoutError{GroupID: group.ID, Problems: problems}
+	}
+
+	return List{
+		Snapshot: models.Snapshot{
+			GroupID:      group.ID,
+			CreationDate: creationDate,
+			CreationTime: creationTime,
+			BudgetPlaces: budgetPlaces,
+			FetchedAt:    time.Now(),
+			Entries:      students,
+		},
+		Headers:  s.extractHeaders(doc),
+		Warnings: warnings,
+	}, nil
+}
+
+// extractBudgetPlaces извлекает количество бюджетных мест из HTML.
+// Второе значение false, если ячейка не найдена или число не удалось разобрать
+func (s *MgsuSource) extractBudgetPlaces(doc *goquery.Document) (int, bool) {
+	// Ищем ячейку с текстом "Всего мест: X."
+	budgetText := ""
+	doc.Find("td").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if strings.Contains(text, "Всего мест:") {
+			budgetText = text
+			return
+		}
+	})
+
+	// Извлекаем число из текста формата "Всего мест: 107."
+	if budgetText != "" {
+		// Ищем паттерн "Всего мест: число."
+		if strings.HasPrefix(budgetText, "Всего мест:") {
+			// Убираем "Всего мест: " и "."
+			numberPart := strings.TrimPrefix(budgetText, "Всего мест:")
+			numberPart = strings.TrimSpace(numberPart)
+			numberPart = strings.TrimSuffix(numberPart, ".")
+
+			if num, err := strconv.Atoi(numberPart); err == nil {
+				if num > 0 && num < 1000 { // разумные границы для количества мест
+					return num, true
+				}
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// extractCreationDateTime извлекает дату и время создания списка из HTML
+func (s *MgsuSource) extractCreationDateTime(doc *goquery.Document) (string, string) {
+	// Ищем ячейку с текстом "Дата формирования - X. Время формирования - Y."
+	var creationDate, creationTime string
+
+	doc.Find("td").Each(func(i int, s *goquery.Selection) {
+		text := strings.TrimSpace(s.Text())
+		if strings.Contains(text, "Дата формирования") && strings.Contains(text, "Время формирования") {
+			// Парсим строку формата "Дата формирования - 31.07.2025. Время формирования - 10:01:01."
+
+			// Ищем дату после "Дата формирования - "
+			if dateStart := strings.Index(text, "Дата формирования - "); dateStart != -1 {
+				dateStart += len("Дата формирования - ")
+				// Ищем следующую точку после даты
+				if dateEnd := strings.Index(text[dateStart:], ". Время формирования"); dateEnd != -1 {
+					creationDate = strings.TrimSpace(text[dateStart : dateStart+dateEnd])
+				}
+			}
+
+			// Ищем время после "Время формирования - "
+			if timeStart := strings.Index(text, "Время формирования - "); timeStart != -1 {
+				timeStart += len("Время формирования - ")
+				// Ищем следующую точку после времени
+				if timeEnd := strings.Index(text[timeStart:], "."); timeEnd != -1 {
+					creationTime = strings.TrimSpace(text[timeStart : timeStart+timeEnd])
+				} else {
+					// Если точки нет, берем до конца строки
+					creationTime = strings.TrimSpace(text[timeStart:])
+				}
+			}
+			return
+		}
+	})
+
+	return creationDate, creationTime
+}
+
+// extractHeaders возвращает тексты заголовков таблицы студентов
+func (s *MgsuSource) extractHeaders(doc *goquery.Document) []string {
+	var headers []string
+	doc.Find("tr.header-row").First().Find("th").Each(func(i int, th *goquery.Selection) {
+		headers = append(headers, strings.Join(strings.Fields(th.Text()), " "))
+	})
+	return headers
+}
+
+// extractGroupName возвращает текст ячейки "Конкурсная группа - ..."
+func (s *MgsuSource) extractGroupName(doc *goquery.Document) (string, bool) {
+	var groupName string
+	found := false
+	doc.Find("td").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		text := strings.TrimSpace(s.Text())
+		if strings.Contains(text, "Конкурсная группа") {
+			groupName = strings.TrimSpace(strings.TrimPrefix(text, "Конкурсная группа - "))
+			found = true
+			return false
+		}
+		return true
+	})
+	return groupName, found
+}
+
+// parseStudentTable парсит таблицу студентов, сопоставляя столбцы по заголовкам
+func (s *MgsuSource) parseStudentTable(doc *goquery.Document) ([]models.StudentEntry, error) {
+	var students []models.StudentEntry
+	var mappingErr error
+
+	// Ищем таблицу с данными
+	doc.Find("table").Each(func(i int, table *goquery.Selection) {
+		headerCells := table.Find("tr.header-row th")
+		if headerCells.Length() == 0 {
+			return
+		}
+
+		var headers []string
+		headerCells.Each(func(j int, th *goquery.Selection) {
+			headers = append(headers, th.Text())
+		})
+
+		// Проверяем, что это нужная нам таблица, по наличию обязательных столбцов
+		mapper, err := newColumnMapper(headers)
+		if err != nil {
+			mappingErr = err
+			return
+		}
+
+		// Парсим строки данных
+		table.Find("tr.data-row").Each(func(j int, row *goquery.Selection) {
+			var cells []string
+			row.Find("td").Each(func(k int, td *goquery.Selection) {
+				cells = append(cells, td.Text())
+			})
+			students = append(students, mapper.Entry(cells))
+		})
+	})
+
+	if len(students) == 0 {
+		if mappingErr != nil {
+			return nil, mappingErr
+		}
+		return nil, fmt.Errorf("таблица студентов не найдена")
+	}
+
+	return students, nil
+}
@@ -0,0 +1,150 @@
+package sources
+
+import (
+	"bot/models"
+	"fmt"
+	"strings"
+)
+
+// studentColumn описывает известный столбец конкурсного списка
+type studentColumn struct {
+	name     string   // Название для сообщений об ошибках
+	aliases  []string // Нормализованные варианты заголовка
+	prefix   bool     // Заголовок может продолжаться после варианта (например, ППР со ссылкой на статью)
+	required bool
+	scoreSum bool // Сумма баллов: за ней на странице идут столбцы вступительных испытаний
+	subject  bool // Вступительное испытание
+	field    func(entry *models.StudentEntry) *string
+}
+
+// studentColumns известные заголовки таблицы. Наборы столбцов различаются между конкурсными группами
+// (целевая и особая квота, разные вступительные испытания), поэтому столбцы ищутся по заголовкам
+var studentColumns = []studentColumn{
+	{name: "№", aliases: []string{"№", "n", "№ п/п"}, field: func(e *models.StudentEntry) *string { return &e.Number }},
+	{name: "Уникальный код", aliases: []string{"уникальный код"}, required: true, field: func(e *models.StudentEntry) *string { return &e.UniqueCode }},
+	{name: "Приоритет", aliases: []string{"приоритет"}, field: func(e *models.StudentEntry) *string { return &e.Priority }},
+	{name: "Согласие на зачисление", aliases: []string{"согласие на зачисление"}, field: func(e *models.StudentEntry) *string { return &e.AdmissionConsent }},
+	{name: "Высший проходной приоритет", aliases: []string{"высший проходной приоритет"}, field: func(e *models.StudentEntry) *string { return &e.HighPassingPriority }},
+	{name: "Это высший проходной приоритет", aliases: []string{"это высший проходной приоритет"}, required: true, field: func(e *models.StudentEntry) *string { return &e.IsHighPassingPriority }},
+	{name: "Основной высший приоритет", aliases: []string{"основной высший приоритет"}, field: func(e *models.StudentEntry) *string { return &e.MainHighPriority }},
+	{name: "Это основной высший приоритет", aliases: []string{"это основной высший приоритет"}, field: func(e *models.StudentEntry) *string { return &e.IsMainHighPriority }},
+	{name: "Сумма баллов", aliases: []string{"сумма баллов", "сумма конкурсных баллов"}, required: true, scoreSum: true, field: func(e *models.StudentEntry) *string { return &e.TotalScore }},
+	{name: "Сумма по предметам", aliases: []string{"сумма по предметам", "сумма баллов по предметам"}, scoreSum: true, field: func(e *models.StudentEntry) *string { return &e.SubjectScore }},
+	{name: "Математика", aliases: []string{"матем", "математика"}, subject: true, field: func(e *models.StudentEntry) *string { return &e.Math }},
+	{name: "Информатика", aliases: []string{"ииикт", "информатика"}, subject: true, field: func(e *models.StudentEntry) *string { return &e.IT }},
+	{name: "Русский язык", aliases: []string{"русяз", "русский язык"}, subject: true, field: func(e *models.StudentEntry) *string { return &e.Russian }},
+	{name: "Общие ИД", aliases: []string{"общие ид"}, field: func(e *models.StudentEntry) *string { return &e.GeneralAchievements }},
+	{name: "Основание БВИ", aliases: []string{"основание бви"}, prefix: true, field: func(e *models.StudentEntry) *string { return &e.BVIBasis }},
+	{name: "ППР (ч.9)", aliases: []string{"ппр (ч.9", "ппр (ч. 9"}, prefix: true, field: func(e *models.StudentEntry) *string { return &e.PPR9 }},
+	{name: "ППР (ч.10)", aliases: []string{"ппр (ч.10", "ппр (ч. 10"}, prefix: true, field: func(e *models.StudentEntry) *string { return &e.PPR10 }},
+}
+
+// columnMapper сопоставляет номера ячеек строки с полями StudentEntry
+type columnMapper struct {
+	fields   map[int]func(entry *models.StudentEntry) *string
+	subjects []subjectColumn // Столбцы вступительных испытаний в порядке страницы
+	extra    map[int]string  // номер ячейки -> заголовок неизвестного столбца
+}
+
+type subjectColumn struct {
+	index int
+	name  string
+}
+
+// newColumnMapper строит сопоставление по текстам заголовков и сообщает об отсутствующих обязательных столбцах.
+// Вступительными испытаниями считаются известные предметы и неизвестные столбцы, которые идут сразу
+// за суммой баллов до следующего известного столбца: набор испытаний у групп разный
+func newColumnMapper(headers []string) (*columnMapper, error) {
+	mapper := &columnMapper{
+		fields: make(map[int]func(entry *models.StudentEntry) *string),
+		extra:  make(map[int]string),
+	}
+
+	found := make(map[string]bool)
+	afterSum := false
+	for i, header := range headers {
+		name := strings.Join(strings.Fields(header), " ")
+		column, ok := matchColumn(header)
+		if ok && found[column.name] {
+			mapper.extra[i] = name
+			continue
+		}
+		if !ok {
+			if afterSum {
+				mapper.subjects = append(mapper.subjects, subjectColumn{index: i, name: name})
+			} else {
+				mapper.extra[i] = name
+			}
+			continue
+		}
+
+		found[column.name] = true
+		mapper.fields[i] = column.field
+		if column.subject {
+			mapper.subjects = append(mapper.subjects, subjectColumn{index: i, name: name})
+		}
+		afterSum = column.scoreSum || (afterSum && column.subject)
+	}
+
+	var missing []string
+	for _, column := range studentColumns {
+		if column.required && !found[column.name] {
+			missing = append(missing, column.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("в таблице нет обязательных столбцов: %s", strings.Join(missing, ", "))
+	}
+
+	return mapper, nil
+}
+
+// Entry заполняет запись о студенте значениями ячеек строки
+func (m *columnMapper) Entry(cells []string) models.StudentEntry {
+	var entry models.StudentEntry
+	for _, subject := range m.subjects {
+		score := ""
+		if subject.index < len(cells) {
+			score = strings.TrimSpace(cells[subject.index])
+		}
+		entry.Subjects = append(entry.Subjects, models.SubjectScore{Name: subject.name, Score: score})
+	}
+	for i, cell := range cells {
+		value := strings.TrimSpace(cell)
+		if field, ok := m.fields[i]; ok {
+			*field(&entry) = value
+			continue
+		}
+		if header, ok := m.extra[i]; ok && value != "" {
+			if entry.Extra == nil {
+				entry.Extra = make(map[string]string)
+			}
+			entry.Extra[header] = value
+		}
+	}
+	return entry
+}
+
+// matchColumn ищет известный столбец по заголовку. Заголовки предметов бывают вида "Матем / ЧиИГ",
+// поэтому каждая часть через "/" проверяется отдельно
+func matchColumn(header string) (studentColumn, bool) {
+	normalized := normalizeHeader(header)
+	parts := append([]string{normalized}, strings.Split(normalized, "/")...)
+
+	for _, column := range studentColumns {
+		for _, alias := range column.aliases {
+			for _, part := range parts {
+				part = strings.TrimSpace(part)
+				if part == alias || (column.prefix && strings.HasPrefix(part, alias)) {
+					return column, true
+				}
+			}
+		}
+	}
+	return studentColumn{}, false
+}
+
+func normalizeHeader(header string) string {
+	header = strings.ToLower(strings.Join(strings.Fields(header), " "))
+	return strings.ReplaceAll(header, "ё", "е")
+}
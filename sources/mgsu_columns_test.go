@@ -0,0 +1,122 @@
+package sources
+
+import (
+	"bot/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchColumn(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string // Название столбца, пусто - столбец неизвестен
+	}{
+		{header: "Уникальный код", want: "Уникальный код"},
+		{header: "  УНИКАЛЬНЫЙ\n код ", want: "Уникальный код"},
+		{header: "Сумма конкурсных баллов", want: "Сумма баллов"},
+		{header: "Матем / ЧиИГ", want: "Математика"},
+		{header: "ИиИКТ / Физика", want: "Информатика"},
+		{header: "Физика", want: ""},
+		{header: "ЧиИГ", want: ""},
+		{header: "ППР (ч.9 ст.71 Федерального закона)", want: "ППР (ч.9)"},
+		{header: "ППР (ч. 10 ст. 71)", want: "ППР (ч.10)"},
+		{header: "Приоритет целевой квоты", want: ""},
+		{header: "Основание БВИ", want: "Основание БВИ"},
+		{header: "Основание БВИ (олимпиада)", want: "Основание БВИ"},
+		{header: "Химия", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			column, ok := matchColumn(tt.header)
+			if ok != (tt.want != "") || column.name != tt.want {
+				t.Errorf("matchColumn(%q) = %q, %v, ожидался %q", tt.header, column.name, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewColumnMapper(t *testing.T) {
+	required := []string{"Уникальный код", "Это высший проходной приоритет", "Сумма баллов"}
+
+	tests := []struct {
+		name    string
+		headers []string
+		cells   []string
+		want    models.StudentEntry
+		missing []string // Обязательные столбцы, которые должна назвать ошибка
+	}{
+		{
+			name:    "известные столбцы и неизвестные в Extra",
+			headers: append([]string{"№", "Химия", "ППР (ч. 9 ст. 71)", "Общежитие"}, required...),
+			cells:   []string{"1", "85", "да", "", "123", "✓", "250"},
+			want: models.StudentEntry{
+				Number: "1", PPR9: "да", UniqueCode: "123", IsHighPassingPriority: "✓", TotalScore: "250",
+				Extra: map[string]string{"Химия": "85"},
+			},
+		},
+		{
+			name: "вступительные испытания в порядке страницы",
+			headers: []string{
+				"Уникальный код", "Это высший проходной приоритет", "Сумма баллов", "Сумма по предметам",
+				"РусЯз", "Физика", "Химия", "Общие ИД", "Общежитие",
+			},
+			cells: []string{"123", "✓", "250", "240", "80", "90", "70", "10", "да"},
+			want: models.StudentEntry{
+				UniqueCode: "123", IsHighPassingPriority: "✓", TotalScore: "250", SubjectScore: "240",
+				Russian: "80", GeneralAchievements: "10",
+				Subjects: []models.SubjectScore{{Name: "РусЯз", Score: "80"}, {Name: "Физика", Score: "90"}, {Name: "Химия", Score: "70"}},
+				Extra:    map[string]string{"Общежитие": "да"},
+			},
+		},
+		{
+			name:    "неизвестные столбцы до суммы баллов не испытания",
+			headers: []string{"Химия", "Уникальный код", "Это высший проходной приоритет", "Сумма баллов", "Матем / ЧиИГ"},
+			cells:   []string{"85", "123", "✓", "250", "90"},
+			want: models.StudentEntry{
+				UniqueCode: "123", IsHighPassingPriority: "✓", TotalScore: "250", Math: "90",
+				Subjects: []models.SubjectScore{{Name: "Матем / ЧиИГ", Score: "90"}},
+				Extra:    map[string]string{"Химия": "85"},
+			},
+		},
+		{
+			name:    "повторный столбец попадает в Extra",
+			headers: append([]string{"Приоритет", "Приоритет"}, required...),
+			cells:   []string{"1", "2", "123", "✓", "250"},
+			want: models.StudentEntry{
+				Priority: "1", UniqueCode: "123", IsHighPassingPriority: "✓", TotalScore: "250",
+				Extra: map[string]string{"Приоритет": "2"},
+			},
+		},
+		{
+			name:    "нет обязательных столбцов",
+			headers: []string{"№", "Уникальный код", "Приоритет"},
+			missing: []string{"Это высший проходной приоритет", "Сумма баллов"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper, err := newColumnMapper(tt.headers)
+			if len(tt.missing) > 0 {
+				if err == nil {
+					t.Fatal("ожидалась ошибка об отсутствующих столбцах")
+				}
+				for _, name := range tt.missing {
+					if !strings.Contains(err.Error(), name) {
+						t.Errorf("ошибка %q не называет столбец %q", err, name)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := mapper.Entry(tt.cells); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("запись %+v, ожидалась %+v", got, tt.want)
+			}
+		})
+	}
+}
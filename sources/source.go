@@ -0,0 +1,83 @@
+package sources
+
+import (
+	"bot/catalog"
+	"bot/fetcher"
+	"bot/models"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ListSource загружает и разбирает конкурсные списки одного вуза
+type ListSource interface {
+	// Name идентификатор источника, совпадает с полем Source конкурсной группы
+	Name() string
+
+	// Fetch загружает страницу списка. Если validators заданы, запрос условный
+	// и неизменившаяся страница возвращается с NotModified = true
+	Fetch(ctx context.Context, group catalog.Group, validators fetcher.Validators) (*fetcher.Response, error)
+
+	// Parse разбирает загруженную страницу в нормализованный список.
+	// Если структура страницы не совпадает с ожидаемой, возвращается *LayoutError
+	Parse(group catalog.Group, body []byte) (List, error)
+}
+
+// List разобранный конкурсный список
+type List struct {
+	Snapshot models.Snapshot
+	Headers  []string // Заголовки таблицы, по ним отслеживаются изменения структуры
+	Warnings []string // Некритичные расхождения со структурой, о которых стоит знать администраторам
+}
+
+// LayoutError означает, что страница конкурсного списка не соответствует ожидаемой структуре
+// и числам с нее нельзя доверять
+type LayoutError struct {
+	GroupID  string
+	Problems []string
+}
+
+func (e *LayoutError) Error() string {
+	return "структура страницы списка изменилась: " + strings.Join(e.Problems, "; ")
+}
+
+// Registry хранит источники списков по имени. Группы без указанного источника
+// обслуживает источник по умолчанию
+type Registry struct {
+	mutex         sync.RWMutex
+	sources       map[string]ListSource
+	defaultSource string
+}
+
+func NewRegistry(defaultSource ListSource) *Registry {
+	registry := &Registry{
+		sources:       make(map[string]ListSource),
+		defaultSource: defaultSource.Name(),
+	}
+	registry.Register(defaultSource)
+	return registry
+}
+
+// Register добавляет источник, заменяя уже зарегистрированный с тем же именем
+func (r *Registry) Register(source ListSource) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sources[source.Name()] = source
+}
+
+// For возвращает источник, который обслуживает конкурсную группу
+func (r *Registry) For(group catalog.Group) (ListSource, error) {
+	name := group.Source
+	if name == "" {
+		name = r.defaultSource
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	source, ok := r.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("неизвестный источник списков %q для группы %s", name, group.ID)
+	}
+	return source, nil
+}
@@ -0,0 +1,458 @@
+package storage
+
+import (
+	"bot/catalog"
+	"bot/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	subscriptionsBucket = []byte("subscriptions")
+	userCodesBucket     = []byte("user_codes")
+	userGroupsBucket    = []byte("user_groups")
+	lastCreationBucket  = []byte("last_creation")
+	groupsBucket        = []byte("groups")
+	studentInfoBucket   = []byte("student_info")
+	snapshotsBucket     = []byte("snapshots")
+	layoutsBucket       = []byte("layouts")
+	listHashesBucket    = []byte("list_hashes")
+	positionModesBucket = []byte("position_modes")
+	mutedGroupsBucket   = []byte("muted_groups")
+
+	catalogKey = []byte("catalog")
+)
+
+// BoltStorage реализация Storage поверх файла BoltDB
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// NewBoltStorage открывает (или создает) файл базы данных по указанному пути
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("ошибка создания каталога базы данных: %v", err)
+		}
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия базы данных: %v", err)
+	}
+
+	// Создаем все необходимые бакеты заранее, чтобы чтение не проверяло их наличие
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{subscriptionsBucket, userCodesBucket, userGroupsBucket, lastCreationBucket, groupsBucket, studentInfoBucket, snapshotsBucket, layoutsBucket, listHashesBucket, positionModesBucket, mutedGroupsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ошибка инициализации базы данных: %v", err)
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+func (s *BoltStorage) LoadSubscriptions() (map[int64]int, error) {
+	return s.loadChatInts(subscriptionsBucket)
+}
+
+func (s *BoltStorage) SaveSubscription(chatID int64, uniqueCode int) error {
+	return s.putChatInt(subscriptionsBucket, chatID, uniqueCode)
+}
+
+func (s *BoltStorage) DeleteSubscription(chatID int64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(subscriptionsBucket).Delete(chatKey(chatID))
+	})
+}
+
+func (s *BoltStorage) LoadUserCodes() (map[int64]int, error) {
+	return s.loadChatInts(userCodesBucket)
+}
+
+func (s *BoltStorage) SaveUserCode(chatID int64, uniqueCode int) error {
+	return s.putChatInt(userCodesBucket, chatID, uniqueCode)
+}
+
+func (s *BoltStorage) LoadPositionModes() (map[int64]models.PositionMode, error) {
+	values, err := s.loadChatInts(positionModesBucket)
+	if err != nil {
+		return nil, err
+	}
+	modes := make(map[int64]models.PositionMode, len(values))
+	for chatID, value := range values {
+		modes[chatID] = models.PositionMode(value)
+	}
+	return modes, nil
+}
+
+func (s *BoltStorage) SavePositionMode(chatID int64, mode models.PositionMode) error {
+	return s.putChatInt(positionModesBucket, chatID, int(mode))
+}
+
+func (s *BoltStorage) LoadUserGroups() (map[int64][]string, error) {
+	return s.loadChatGroups(userGroupsBucket)
+}
+
+func (s *BoltStorage) SaveUserGroups(chatID int64, groupIDs []string) error {
+	return s.putChatGroups(userGroupsBucket, chatID, groupIDs)
+}
+
+func (s *BoltStorage) LoadMutedGroups() (map[int64][]string, error) {
+	return s.loadChatGroups(mutedGroupsBucket)
+}
+
+func (s *BoltStorage) SaveMutedGroups(chatID int64, groupIDs []string) error {
+	return s.putChatGroups(mutedGroupsBucket, chatID, groupIDs)
+}
+
+func (s *BoltStorage) LoadLastCreationDateTimes() (map[string]string, error) {
+	result := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(lastCreationBucket).ForEach(func(k, v []byte) error {
+			result[string(k)] = string(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *BoltStorage) SaveLastCreationDateTime(groupID string, value string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(lastCreationBucket).Put([]byte(groupID), []byte(value))
+	})
+}
+
+func (s *BoltStorage) LoadListHashes() (map[string]string, error) {
+	result := make(map[string]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(listHashesBucket).ForEach(func(k, v []byte) error {
+			result[string(k)] = string(v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *BoltStorage) SaveListHash(groupID string, hash string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(listHashesBucket).Put([]byte(groupID), []byte(hash))
+	})
+}
+
+func (s *BoltStorage) LoadGroups() ([]catalog.Group, error) {
+	var groups []catalog.Group
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(groupsBucket).Get(catalogKey)
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &groups)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (s *BoltStorage) SaveGroups(groups []catalog.Group) error {
+	data, err := json.Marshal(groups)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(groupsBucket).Put(catalogKey, data)
+	})
+}
+
+func (s *BoltStorage) LoadStudentInfo(chatID int64, groupID string) (*models.StudentInfo, error) {
+	var info *models.StudentInfo
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(studentInfoBucket).Get(chatGroupKey(chatID, groupID))
+		if data == nil {
+			return nil
+		}
+		info = &models.StudentInfo{}
+		return json.Unmarshal(data, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (s *BoltStorage) SaveStudentInfo(chatID int64, groupID string, info models.StudentInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(studentInfoBucket).Put(chatGroupKey(chatID, groupID), data)
+	})
+}
+
+func (s *BoltStorage) DeleteStudentInfo(chatID int64) error {
+	prefix := chatGroupKey(chatID, "")
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(studentInfoBucket)
+
+		var keys [][]byte
+		cursor := bucket.Cursor()
+		for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for _, key := range keys {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Версии списков хранятся во вложенном бакете на каждую группу, ключ - Snapshot.Key()
+func (s *BoltStorage) SaveSnapshot(snapshot models.Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.Bucket(snapshotsBucket).CreateBucketIfNotExists([]byte(snapshot.GroupID))
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(snapshot.Key()), data)
+	})
+}
+
+func (s *BoltStorage) LoadSnapshots(groupID string) ([]models.Snapshot, error) {
+	var snapshots []models.Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(snapshotsBucket).Bucket([]byte(groupID))
+		if bucket == nil {
+			return nil
+		}
+		// Ключи сортируются по времени формирования, поэтому ForEach идет в хронологическом порядке
+		return bucket.ForEach(func(k, v []byte) error {
+			var snapshot models.Snapshot
+			if err := json.Unmarshal(v, &snapshot); err != nil {
+				return fmt.Errorf("некорректная версия списка %s/%s: %v", groupID, k, err)
+			}
+			snapshots = append(snapshots, snapshot)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func (s *BoltStorage) LoadLatestSnapshot(groupID string) (*models.Snapshot, error) {
+	var snapshot *models.Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(snapshotsBucket).Bucket([]byte(groupID))
+		if bucket == nil {
+			return nil
+		}
+		k, v := bucket.Cursor().Last()
+		if k == nil {
+			return nil
+		}
+		snapshot = &models.Snapshot{}
+		if err := json.Unmarshal(v, snapshot); err != nil {
+			return fmt.Errorf("некорректная версия списка %s/%s: %v", groupID, k, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func (s *BoltStorage) LoadSnapshotsSince(groupID string, since time.Time) ([]models.Snapshot, error) {
+	var snapshots []models.Snapshot
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(snapshotsBucket).Bucket([]byte(groupID))
+		if bucket == nil {
+			return nil
+		}
+		cursor := bucket.Cursor()
+		for k, v := cursor.Seek([]byte(models.SnapshotKey(since))); k != nil; k, v = cursor.Next() {
+			var snapshot models.Snapshot
+			if err := json.Unmarshal(v, &snapshot); err != nil {
+				return fmt.Errorf("некорректная версия списка %s/%s: %v", groupID, k, err)
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+func (s *BoltStorage) PruneSnapshots(groupID string, maxAge time.Duration, maxCount int) (int, error) {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(snapshotsBucket).Bucket([]byte(groupID))
+		if bucket == nil {
+			return nil
+		}
+
+		var keys [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// Сначала отбрасываем лишние по количеству (самые старые), затем - по возрасту
+		var expired [][]byte
+		if maxCount > 0 && len(keys) > maxCount {
+			expired = append(expired, keys[:len(keys)-maxCount]...)
+			keys = keys[len(keys)-maxCount:]
+		}
+		if maxAge > 0 {
+			cutoff := models.SnapshotKey(time.Now().Add(-maxAge))
+			for _, key := range keys {
+				if string(key) < cutoff {
+					expired = append(expired, key)
+				}
+			}
+		}
+
+		for _, key := range expired {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	return removed, err
+}
+
+func (s *BoltStorage) LoadLayouts() (map[string][]string, error) {
+	result := make(map[string][]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(layoutsBucket).ForEach(func(k, v []byte) error {
+			var headers []string
+			if err := json.Unmarshal(v, &headers); err != nil {
+				return fmt.Errorf("некорректная структура списка %s: %v", k, err)
+			}
+			result[string(k)] = headers
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *BoltStorage) SaveLayout(groupID string, headers []string) error {
+	data, err := json.Marshal(headers)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(layoutsBucket).Put([]byte(groupID), data)
+	})
+}
+
+func (s *BoltStorage) Close() error {
+	return s.db.Close()
+}
+
+// loadChatInts читает бакет вида chatID -> число
+func (s *BoltStorage) loadChatInts(bucket []byte) (map[int64]int, error) {
+	result := make(map[int64]int)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			chatID, err := strconv.ParseInt(string(k), 10, 64)
+			if err != nil {
+				return fmt.Errorf("некорректный chatID %q в бакете %s: %v", k, bucket, err)
+			}
+			value, err := strconv.Atoi(string(v))
+			if err != nil {
+				return fmt.Errorf("некорректное значение для chatID %d в бакете %s: %v", chatID, bucket, err)
+			}
+			result[chatID] = value
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// putChatInt записывает значение вида chatID -> число
+func (s *BoltStorage) putChatInt(bucket []byte, chatID int64, value int) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(chatKey(chatID), []byte(strconv.Itoa(value)))
+	})
+}
+
+func (s *BoltStorage) loadChatGroups(bucket []byte) (map[int64][]string, error) {
+	result := make(map[int64][]string)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			chatID, err := strconv.ParseInt(string(k), 10, 64)
+			if err != nil {
+				return fmt.Errorf("некорректный chatID %q в бакете %s: %v", k, bucket, err)
+			}
+			var groupIDs []string
+			if err := json.Unmarshal(v, &groupIDs); err != nil {
+				return fmt.Errorf("некорректный список групп для chatID %d: %v", chatID, err)
+			}
+			result[chatID] = groupIDs
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *BoltStorage) putChatGroups(bucket []byte, chatID int64, groupIDs []string) error {
+	data, err := json.Marshal(groupIDs)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(chatKey(chatID), data)
+	})
+}
+
+func chatKey(chatID int64) []byte {
+	return []byte(strconv.FormatInt(chatID, 10))
+}
+
+func chatGroupKey(chatID int64, groupID string) []byte {
+	return []byte(strconv.FormatInt(chatID, 10) + ":" + groupID)
+}
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"bot/catalog"
+	"bot/models"
+	"time"
+)
+
+// Storage хранит состояние бота, которое должно переживать перезапуски
+type Storage interface {
+	// LoadSubscriptions возвращает подписки в виде chatID -> uniqueCode
+	LoadSubscriptions() (map[int64]int, error)
+	SaveSubscription(chatID int64, uniqueCode int) error
+	DeleteSubscription(chatID int64) error
+
+	// LoadUserCodes возвращает коды пользователей в виде chatID -> uniqueCode
+	LoadUserCodes() (map[int64]int, error)
+	SaveUserCode(chatID int64, uniqueCode int) error
+
+	// LoadPositionModes возвращает режимы подсчета позиции для уведомлений в виде chatID -> режим
+	LoadPositionModes() (map[int64]models.PositionMode, error)
+	SavePositionMode(chatID int64, mode models.PositionMode) error
+
+	// LoadUserGroups возвращает отслеживаемые конкурсные группы в виде chatID -> []groupID
+	LoadUserGroups() (map[int64][]string, error)
+	SaveUserGroups(chatID int64, groupIDs []string) error
+
+	// LoadMutedGroups возвращает группы, уведомления по которым пользователь отключил, в виде chatID -> []groupID
+	LoadMutedGroups() (map[int64][]string, error)
+	SaveMutedGroups(chatID int64, groupIDs []string) error
+
+	// LoadLastCreationDateTimes возвращает дату и время формирования последнего
+	// просмотренного списка в виде groupID -> "дата время"
+	LoadLastCreationDateTimes() (map[string]string, error)
+	SaveLastCreationDateTime(groupID string, value string) error
+
+	// LoadListHashes возвращает хэш содержимого последнего просмотренного списка в виде groupID -> хэш
+	LoadListHashes() (map[string]string, error)
+	SaveListHash(groupID string, hash string) error
+
+	// LoadGroups возвращает сохраненный каталог конкурсных групп (nil, если он еще не сохранялся)
+	LoadGroups() ([]catalog.Group, error)
+	SaveGroups(groups []catalog.Group) error
+
+	// LoadStudentInfo возвращает последнюю показанную пользователю информацию по группе (nil, если ее нет)
+	LoadStudentInfo(chatID int64, groupID string) (*models.StudentInfo, error)
+	SaveStudentInfo(chatID int64, groupID string, info models.StudentInfo) error
+	// DeleteStudentInfo удаляет показанную пользователю информацию по всем группам
+	DeleteStudentInfo(chatID int64) error
+
+	// SaveSnapshot сохраняет версию конкурсного списка (повторное сохранение той же версии ее перезаписывает)
+	SaveSnapshot(snapshot models.Snapshot) error
+	// LoadSnapshots возвращает все сохраненные версии списка группы в порядке формирования
+	LoadSnapshots(groupID string) ([]models.Snapshot, error)
+	// LoadLatestSnapshot возвращает последнюю сохраненную версию списка группы (nil, если версий нет)
+	LoadLatestSnapshot(groupID string) (*models.Snapshot, error)
+	// LoadSnapshotsSince возвращает версии списка группы, сформированные не раньше since
+	LoadSnapshotsSince(groupID string, since time.Time) ([]models.Snapshot, error)
+	// PruneSnapshots удаляет версии старше maxAge и сверх maxCount последних (нулевые значения отключают ограничение)
+	PruneSnapshots(groupID string, maxAge time.Duration, maxCount int) (int, error)
+
+	// LoadLayouts возвращает заголовки таблицы последней корректной версии списка в виде groupID -> заголовки
+	LoadLayouts() (map[string][]string, error)
+	SaveLayout(groupID string, headers []string) error
+
+	Close() error
+}
@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// SecretHeader заголовок, в котором Telegram присылает секрет, указанный при установке вебхука
+const SecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxUpdateSize ограничение на размер тела запроса с обновлением
+const maxUpdateSize = 1 << 20
+
+// Таймауты HTTP-сервера, чтобы медленные клиенты не держали соединения бесконечно
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 10 * time.Second
+	idleTimeout       = 60 * time.Second
+)
+
+// Server принимает обновления Telegram по HTTP и передает их в канал, который читает BotHandler
+type Server struct {
+	path    string
+	secret  string
+	updates chan tgbotapi.Update
+}
+
+func New(path string, secret string) *Server {
+	return &Server{
+		path:    path,
+		secret:  secret,
+		updates: make(chan tgbotapi.Update, 100),
+	}
+}
+
+// Updates возвращает канал обновлений, совместимый с GetUpdatesChan
+func (s *Server) Updates() tgbotapi.UpdatesChannel {
+	return s.updates
+}
+
+// Start открывает порт и обслуживает запросы в отдельной горутине. Порт открывается сразу,
+// поэтому после успешного Start вебхук можно регистрировать: Telegram не получит отказ в соединении
+func (s *Server) Start(listenAddr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.path, s)
+
+	listener, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		return fmt.Errorf("ошибка запуска сервера вебхука: %v", err)
+	}
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			panic(fmt.Sprintf("ошибка сервера вебхука: %v", err))
+		}
+	}()
+	return nil
+}
+
+// ServeHTTP проверяет секрет и передает обновление в канал. Если канал заполнен, запрос ждет:
+// Telegram не пришлет следующее обновление, пока не получит ответ, поэтому обновления не теряются
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(SecretHeader)), []byte(s.secret)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxUpdateSize)).Decode(&update); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case s.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		// Telegram повторит обновление, если не получил ответ
+	}
+}
+
+// Register устанавливает вебхук бота на publicURL с секретом для заголовка SecretHeader.
+// Используется прямой запрос, потому что WebhookConfig библиотеки не поддерживает secret_token
+func Register(bot *tgbotapi.BotAPI, publicURL string, secret string) error {
+	params := tgbotapi.Params{"url": publicURL}
+	params.AddNonEmpty("secret_token", secret)
+
+	if _, err := bot.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("ошибка установки вебхука: %v", err)
+	}
+	return nil
+}
+
+// Remove удаляет вебхук, чтобы бот снова мог получать обновления через long polling
+func Remove(bot *tgbotapi.BotAPI) error {
+	info, err := bot.GetWebhookInfo()
+	if err != nil {
+		return fmt.Errorf("ошибка получения информации о вебхуке: %v", err)
+	}
+	if info.URL == "" {
+		return nil
+	}
+	if _, err := bot.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("ошибка удаления вебхука: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "s3cret"
+
+func TestServeHTTPRejectsInvalidRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		secret string
+		body   string
+		want   int
+	}{
+		{name: "без секрета", method: http.MethodPost, body: `{"update_id":1}`, want: http.StatusForbidden},
+		{name: "неверный секрет", method: http.MethodPost, secret: "wrong", body: `{"update_id":1}`, want: http.StatusForbidden},
+		{name: "не POST", method: http.MethodGet, secret: testSecret, want: http.StatusMethodNotAllowed},
+		{name: "некорректный JSON", method: http.MethodPost, secret: testSecret, body: `{"update_id":`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := New("/telegram", testSecret)
+			httpServer := httptest.NewServer(server)
+			defer httpServer.Close()
+
+			req, err := http.NewRequest(tt.method, httpServer.URL+"/telegram", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.secret != "" {
+				req.Header.Set(SecretHeader, tt.secret)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.want {
+				t.Errorf("статус %d, ожидался %d", resp.StatusCode, tt.want)
+			}
+			select {
+			case update := <-server.Updates():
+				t.Errorf("отклоненный запрос передал обновление %d", update.UpdateID)
+			default:
+			}
+		})
+	}
+}
+
+func TestServeHTTPDeliversUpdate(t *testing.T) {
+	server := New("/telegram", testSecret)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	body := `{"update_id":42,"message":{"message_id":7,"date":0,"chat":{"id":100,"type":"private"},"text":"Получить"}}`
+	req, err := http.NewRequest(http.MethodPost, httpServer.URL+"/telegram", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(SecretHeader, testSecret)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("статус %d, ожидался %d", resp.StatusCode, http.StatusOK)
+	}
+
+	select {
+	case update := <-server.Updates():
+		if update.UpdateID != 42 || update.Message == nil || update.Message.Text != "Получить" || update.Message.Chat.ID != 100 {
+			t.Errorf("получено неверное обновление: %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("обновление не попало в канал Updates")
+	}
+}